@@ -6,6 +6,8 @@ package auth
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"strings"
@@ -27,49 +29,27 @@ func Only(me string, next http.Handler) http.HandlerFunc {
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
-		auth := r.Header.Get("Authorization")
-		if auth == "" || strings.TrimSpace(auth) == "Bearer" {
-			if r.FormValue("access_token") == "" {
-				w.Header().Set("Content-Type", "application/json")
-				http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
-				return
-			}
-
-			auth = "Bearer " + r.FormValue("access_token")
+		auth := bearer(r)
+		if auth == "" {
+			Error(w, http.StatusUnauthorized, "unauthorized", "no access token was provided")
+			return
 		}
 
-		req, err := http.NewRequest("GET", endpoints.Token.String(), nil)
-		if err != nil {
-			slog.Error("auth make request failed", slog.Any("err", err))
-			http.Error(w, "", http.StatusInternalServerError)
+		tokenData, err := verify(endpoints.Token.String(), auth)
+		if errors.Is(err, errInvalidToken) {
+			slog.Error("auth decode token", slog.Any("err", err))
+			Error(w, http.StatusForbidden, "forbidden", "the access token is not valid")
 			return
 		}
-		req.Header.Add("Authorization", auth)
-
-		resp, err := http.DefaultClient.Do(req)
 		if err != nil {
 			slog.Error("auth request failed", slog.Any("err", err))
-			http.Error(w, "", http.StatusInternalServerError)
-			return
-		}
-		defer resp.Body.Close()
-
-		var tokenData struct {
-			Me       string `json:"me"`
-			ClientID string `json:"client_id"`
-			Scope    string `json:"scope"`
-		}
-		if err := json.NewDecoder(resp.Body).Decode(&tokenData); err != nil {
-			slog.Error("auth decode token", slog.Any("err", err))
-			w.Header().Set("Content-Type", "application/json")
-			http.Error(w, `{"error":"forbidden"}`, http.StatusForbidden)
+			Error(w, http.StatusInternalServerError, "server_error", "the access token could not be verified")
 			return
 		}
 
 		if tokenData.Me != me {
 			slog.Error("token is forbidden", slog.String("me", tokenData.Me))
-			w.Header().Set("Content-Type", "application/json")
-			http.Error(w, `{"error":"forbidden"}`, http.StatusForbidden)
+			Error(w, http.StatusForbidden, "forbidden", "the access token was not issued for "+me)
 			return
 		}
 
@@ -82,6 +62,78 @@ func Only(me string, next http.Handler) http.HandlerFunc {
 	}
 }
 
+// Owner returns a function that reports whether a request has provided
+// authentication for the user specified by me, in the same way as Only. Unlike
+// Only a request without authentication is not rejected, so it can be used to
+// show extra content to the owner of a site.
+func Owner(me string) func(r *http.Request) bool {
+	endpoints, err := indieauth.FindEndpoints(me)
+	if err != nil {
+		slog.Error("find indieauth endpoints", slog.Any("err", err))
+		panic("could not start")
+	}
+
+	return func(r *http.Request) bool {
+		auth := bearer(r)
+		if auth == "" {
+			return false
+		}
+
+		tokenData, err := verify(endpoints.Token.String(), auth)
+		if err != nil {
+			slog.Warn("auth owner", slog.Any("err", err))
+			return false
+		}
+
+		return tokenData.Me == me
+	}
+}
+
+var errInvalidToken = errors.New("invalid token")
+
+type tokenData struct {
+	Me       string `json:"me"`
+	ClientID string `json:"client_id"`
+	Scope    string `json:"scope"`
+}
+
+// bearer returns the value to use for the Authorization header when verifying
+// the token provided with the request, or an empty string if there is no token.
+func bearer(r *http.Request) string {
+	auth := r.Header.Get("Authorization")
+	if auth == "" || strings.TrimSpace(auth) == "Bearer" {
+		if r.FormValue("access_token") == "" {
+			return ""
+		}
+
+		auth = "Bearer " + r.FormValue("access_token")
+	}
+
+	return auth
+}
+
+// verify asks the token endpoint for the details of the token. If the endpoint
+// does not respond with details, then an errInvalidToken is returned.
+func verify(tokenEndpoint, auth string) (data tokenData, err error) {
+	req, err := http.NewRequest("GET", tokenEndpoint, nil)
+	if err != nil {
+		return
+	}
+	req.Header.Add("Authorization", auth)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+
+	if err = json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		err = fmt.Errorf("%w: %w", errInvalidToken, err)
+	}
+
+	return
+}
+
 const scopesKey = "__hawx.me/code/tally-ho:Scopes__"
 const clientKey = "__hawx.me/code/tally-ho:ClientID__"
 
@@ -89,24 +141,30 @@ const clientKey = "__hawx.me/code/tally-ho:ClientID__"
 // listed valid scopes.
 func HasScope(w http.ResponseWriter, r *http.Request, valid ...string) bool {
 	rv := r.Context().Value(scopesKey)
-	if rv == nil {
-		w.Header().Set("Content-Type", "application/json")
-		http.Error(w, `{"error":"insufficient_scope"}`, http.StatusUnauthorized)
-		return false
-	}
-
-	scopes := rv.([]string)
-
-	hasScope := intersects(valid, scopes)
-	if !hasScope {
-		w.Header().Set("Content-Type", "application/json")
-		http.Error(w, `{"error":"insufficient_scope"}`, http.StatusUnauthorized)
+	if rv == nil || !intersects(valid, rv.([]string)) {
+		Error(w, http.StatusUnauthorized, "insufficient_scope",
+			"the access token requires one of the scopes: "+strings.Join(valid, ", "))
 		return false
 	}
 
 	return true
 }
 
+// Error writes a JSON error response, as described by the micropub
+// specification https://www.w3.org/TR/micropub/#error-response.
+func Error(w http.ResponseWriter, status int, code, description string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	json.NewEncoder(w).Encode(struct {
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description,omitempty"`
+	}{
+		Error:            code,
+		ErrorDescription: description,
+	})
+}
+
 // ClientID returns the clientId that was issued for the token in a request that
 // has been authenticated with Only.
 func ClientID(r *http.Request) string {
@@ -263,3 +263,56 @@ func TestAuthenticateClientID(t *testing.T) {
 		})
 	}
 }
+
+func TestOwner(t *testing.T) {
+	for name, req := range testCases("?access_token=abcde", "abcde") {
+		t.Run(name, func(t *testing.T) {
+			me := &meHandler{Token: "abcde"}
+
+			meServer := httptest.NewServer(me)
+			defer meServer.Close()
+			me.Me = meServer.URL
+
+			assert.True(t, Owner(meServer.URL)(req))
+		})
+	}
+}
+
+func TestOwnerWithoutToken(t *testing.T) {
+	me := &meHandler{Token: "abcde"}
+
+	meServer := httptest.NewServer(me)
+	defer meServer.Close()
+	me.Me = meServer.URL
+
+	req := httptest.NewRequest("GET", "http://localhost/", nil)
+
+	assert.False(t, Owner(meServer.URL)(req))
+}
+
+func TestOwnerWithInvalidToken(t *testing.T) {
+	for name, req := range testCases("?access_token=wrong", "wrong") {
+		t.Run(name, func(t *testing.T) {
+			me := &meHandler{Token: "abcde"}
+
+			meServer := httptest.NewServer(me)
+			defer meServer.Close()
+			me.Me = meServer.URL
+
+			assert.False(t, Owner(meServer.URL)(req))
+		})
+	}
+}
+
+func TestOwnerWithTokenForSomeoneElse(t *testing.T) {
+	for name, req := range testCases("?access_token=abcde", "abcde") {
+		t.Run(name, func(t *testing.T) {
+			me := &meHandler{Token: "abcde", Me: "https://someone.example.com/"}
+
+			meServer := httptest.NewServer(me)
+			defer meServer.Close()
+
+			assert.False(t, Owner(meServer.URL)(req))
+		})
+	}
+}
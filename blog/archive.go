@@ -0,0 +1,412 @@
+package blog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"strings"
+	"time"
+
+	"hawx.me/code/numbersix"
+	"hawx.me/code/tally-ho/internal/mfutil"
+)
+
+// The formats that an archive can be written in.
+const (
+	FormatMF2 = "mf2"
+	FormatJF2 = "jf2"
+)
+
+// An Archive contains everything needed to recreate a blog. Entries and
+// mentions are kept with all of their properties, including the hx- properties
+// that are not normally shown, so deleted entries will have hx-deleted and the
+// urls an entry used to have are in hx-aliases. As a mention is identified by
+// its source this is given as hx-source. The history of each entry is kept in
+// Revisions, with the Redirects that have been added and the Tombstones of
+// entries that have been purged.
+type Archive struct {
+	Entries    []map[string][]any
+	Revisions  []Revision
+	Mentions   []map[string][]any
+	Redirects  []Redirect
+	Tombstones []Tombstone
+	Media      []MediaFile
+}
+
+// archiveRevision is the layout of a revision in an archive, with its
+// properties as an item in the same format as the entries.
+type archiveRevision struct {
+	UID       string         `json:"uid"`
+	Version   int            `json:"version"`
+	CreatedAt time.Time      `json:"created-at"`
+	Item      map[string]any `json:"item"`
+}
+
+// mf2Archive is the layout of an archive in mf2 JSON.
+type mf2Archive struct {
+	Items      []map[string]any  `json:"items"`
+	Revisions  []archiveRevision `json:"revisions"`
+	Mentions   []map[string]any  `json:"mentions"`
+	Redirects  []Redirect        `json:"redirects"`
+	Tombstones []Tombstone       `json:"tombstones"`
+	Media      []MediaFile       `json:"media"`
+}
+
+// jf2Archive is the layout of an archive in JF2, as a feed.
+type jf2Archive struct {
+	Type       string            `json:"type"`
+	Children   []map[string]any  `json:"children"`
+	Revisions  []archiveRevision `json:"revisions"`
+	Mentions   []map[string]any  `json:"mentions"`
+	Redirects  []Redirect        `json:"redirects"`
+	Tombstones []Tombstone       `json:"tombstones"`
+	Media      []MediaFile       `json:"media"`
+}
+
+// Export returns an Archive of every entry and mention.
+func (b *Blog) Export() (Archive, error) {
+	var archive Archive
+
+	entries, err := b.entries.List(numbersix.All())
+	if err != nil {
+		return archive, err
+	}
+	for _, group := range numbersix.Grouped(entries) {
+		archive.Entries = append(archive.Entries, group.Properties)
+	}
+
+	if archive.Revisions, err = b.revisions.All(); err != nil {
+		return archive, err
+	}
+
+	mentions, err := b.mentions.List(numbersix.All())
+	if err != nil {
+		return archive, err
+	}
+	for _, group := range numbersix.Grouped(mentions) {
+		group.Properties["hx-source"] = []any{group.Subject}
+		archive.Mentions = append(archive.Mentions, group.Properties)
+	}
+
+	if archive.Redirects, err = b.redirects.List(); err != nil {
+		return archive, err
+	}
+
+	archive.Tombstones, err = b.tombstones.List()
+	return archive, err
+}
+
+// Import adds the entries and mentions in archive to the blog. It does this
+// quietly, so nothing is syndicated, no webmentions are sent and no hub is
+// notified. As entries would otherwise be mixed together the blog must not have
+// any entries.
+func (b *Blog) Import(archive Archive) error {
+	existing, err := b.entries.List(numbersix.All().Limit(1))
+	if err != nil {
+		return err
+	}
+	if len(existing) > 0 {
+		return errors.New("entries can only be imported in to an empty blog")
+	}
+
+	hasRevisions := map[string]bool{}
+	for _, revision := range archive.Revisions {
+		if err := b.revisions.Insert(revision); err != nil {
+			return err
+		}
+		hasRevisions[revision.UID] = true
+	}
+
+	for _, data := range archive.Entries {
+		uid, ok := mfutil.Get(data, "uid").(string)
+		if !ok {
+			return errors.New("entry to import is missing uid")
+		}
+
+		if err := b.entries.SetProperties(uid, data); err != nil {
+			return err
+		}
+		// the history of an entry may not have been kept, in which case it
+		// starts again from now
+		if !hasRevisions[uid] {
+			if err := b.revisions.Save(uid, data); err != nil {
+				return err
+			}
+		}
+		if err := b.search.Index(data); err != nil {
+			return err
+		}
+	}
+
+	for _, data := range archive.Mentions {
+		source, ok := mfutil.Get(data, "hx-source").(string)
+		if !ok {
+			return errors.New("mention to import is missing hx-source")
+		}
+		delete(data, "hx-source")
+
+		if err := b.mentions.SetProperties(source, data); err != nil {
+			return err
+		}
+	}
+
+	for _, redirect := range archive.Redirects {
+		if err := b.redirects.Set(redirect.From, redirect.To); err != nil {
+			return err
+		}
+	}
+
+	for _, tombstone := range archive.Tombstones {
+		if err := b.tombstones.Save(tombstone); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteArchive encodes archive to w in the format, which must be FormatMF2 or
+// FormatJF2.
+func WriteArchive(w io.Writer, archive Archive, format string) error {
+	var v any
+
+	switch format {
+	case FormatMF2:
+		a := mf2Archive{
+			Revisions:  archiveRevisions(archive.Revisions, toMF2),
+			Redirects:  archive.Redirects,
+			Tombstones: archive.Tombstones,
+			Media:      archive.Media,
+		}
+		for _, data := range archive.Entries {
+			a.Items = append(a.Items, toMF2(data))
+		}
+		for _, data := range archive.Mentions {
+			a.Mentions = append(a.Mentions, toMF2(data))
+		}
+		v = a
+
+	case FormatJF2:
+		a := jf2Archive{
+			Type:       "feed",
+			Revisions:  archiveRevisions(archive.Revisions, toJF2),
+			Redirects:  archive.Redirects,
+			Tombstones: archive.Tombstones,
+			Media:      archive.Media,
+		}
+		for _, data := range archive.Entries {
+			a.Children = append(a.Children, toJF2(data))
+		}
+		for _, data := range archive.Mentions {
+			a.Mentions = append(a.Mentions, toJF2(data))
+		}
+		v = a
+
+	default:
+		return fmt.Errorf("unknown archive format %q", format)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func archiveRevisions(revisions []Revision, to func(map[string][]any) map[string]any) []archiveRevision {
+	var converted []archiveRevision
+	for _, revision := range revisions {
+		converted = append(converted, archiveRevision{
+			UID:       revision.UID,
+			Version:   revision.Version,
+			CreatedAt: revision.CreatedAt,
+			Item:      to(revision.Properties),
+		})
+	}
+
+	return converted
+}
+
+// ReadArchive decodes an archive written by WriteArchive, in either format.
+func ReadArchive(r io.Reader) (Archive, error) {
+	var (
+		archive Archive
+		v       struct {
+			Type       string            `json:"type"`
+			Items      []map[string]any  `json:"items"`
+			Children   []map[string]any  `json:"children"`
+			Revisions  []archiveRevision `json:"revisions"`
+			Mentions   []map[string]any  `json:"mentions"`
+			Redirects  []Redirect        `json:"redirects"`
+			Tombstones []Tombstone       `json:"tombstones"`
+			Media      []MediaFile       `json:"media"`
+		}
+	)
+
+	if err := json.NewDecoder(r).Decode(&v); err != nil {
+		return archive, err
+	}
+
+	archive.Redirects = v.Redirects
+	archive.Tombstones = v.Tombstones
+	archive.Media = v.Media
+
+	from := fromMF2
+	items := v.Items
+	if v.Type == "feed" {
+		from = fromJF2
+		items = v.Children
+	}
+
+	for _, item := range items {
+		archive.Entries = append(archive.Entries, from(item))
+	}
+	for _, revision := range v.Revisions {
+		archive.Revisions = append(archive.Revisions, Revision{
+			UID:        revision.UID,
+			Version:    revision.Version,
+			CreatedAt:  revision.CreatedAt,
+			Properties: from(revision.Item),
+		})
+	}
+	for _, item := range v.Mentions {
+		archive.Mentions = append(archive.Mentions, from(item))
+	}
+
+	return archive, nil
+}
+
+// toMF2 returns the entry as an mf2 JSON item, with the type taken from the h
+// property.
+func toMF2(data map[string][]any) map[string]any {
+	h, ok := mfutil.Get(data, "h").(string)
+	if !ok {
+		h = "entry"
+	}
+
+	return map[string]any{
+		"type":       []any{"h-" + h},
+		"properties": data,
+	}
+}
+
+// fromMF2 returns the properties of an mf2 JSON item. The type is not used, as
+// the h property is kept with the others when there is one.
+func fromMF2(item map[string]any) map[string][]any {
+	data := map[string][]any{}
+
+	if properties, ok := item["properties"].(map[string]any); ok {
+		for key, value := range properties {
+			if values, ok := value.([]any); ok {
+				data[key] = values
+			}
+		}
+	}
+
+	return data
+}
+
+// toJF2 returns the entry in JF2, where a property with a single value is not
+// wrapped in an array and embedded microformats are also simplified. The h
+// property is used for the type, but also kept so that it is only read back
+// when there was one.
+func toJF2(data map[string][]any) map[string]any {
+	h, ok := mfutil.Get(data, "h").(string)
+	if !ok {
+		h = "entry"
+	}
+
+	item := map[string]any{"type": h}
+	for key, values := range data {
+		if key == "type" {
+			continue
+		}
+
+		converted := make([]any, len(values))
+		for i, value := range values {
+			converted[i] = jf2Value(value)
+		}
+
+		if len(converted) == 1 {
+			item[key] = converted[0]
+		} else {
+			item[key] = converted
+		}
+	}
+
+	return item
+}
+
+func jf2Value(value any) any {
+	m, ok := value.(map[string]any)
+	if !ok {
+		return value
+	}
+
+	h, ok := mfutil.Get(m, "type").(string)
+	if !ok || !strings.HasPrefix(h, "h-") {
+		return value
+	}
+
+	properties := map[string][]any{}
+	switch typed := m["properties"].(type) {
+	case map[string][]any:
+		maps.Copy(properties, typed)
+	case map[string]any:
+		for key, v := range typed {
+			if values, ok := v.([]any); ok {
+				properties[key] = values
+			}
+		}
+	}
+
+	item := toJF2(properties)
+	item["type"] = strings.TrimPrefix(h, "h-")
+	return item
+}
+
+func fromJF2(item map[string]any) map[string][]any {
+	data := map[string][]any{}
+
+	for key, value := range item {
+		if key == "type" {
+			continue
+		}
+
+		values, ok := value.([]any)
+		if !ok {
+			values = []any{value}
+		}
+
+		converted := make([]any, len(values))
+		for i, v := range values {
+			converted[i] = mf2Value(v)
+		}
+
+		data[key] = converted
+	}
+
+	return data
+}
+
+func mf2Value(value any) any {
+	m, ok := value.(map[string]any)
+	if !ok {
+		return value
+	}
+
+	h, ok := m["type"].(string)
+	if !ok {
+		return value
+	}
+
+	properties := map[string]any{}
+	for key, values := range fromJF2(m) {
+		properties[key] = values
+	}
+
+	return map[string]any{
+		"type":       []any{"h-" + h},
+		"properties": properties,
+	}
+}
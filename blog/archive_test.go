@@ -0,0 +1,185 @@
+package blog
+
+import (
+	"bytes"
+	"database/sql"
+	"testing"
+	"time"
+
+	"hawx.me/code/assert"
+	"hawx.me/code/numbersix"
+)
+
+func testArchive() Archive {
+	return Archive{
+		Entries: []map[string][]any{
+			{
+				"h":        {"entry"},
+				"uid":      {"1"},
+				"url":      {"http://example.com/1"},
+				"content":  {map[string]any{"html": "<p>hey</p>", "text": "hey"}},
+				"category": {"a", "b"},
+				"like-of": {map[string]any{
+					"type": []any{"h-cite"},
+					"properties": map[string]any{
+						"name": []any{"A thing"},
+						"url":  []any{"http://other.example.com/thing"},
+					},
+				}},
+			},
+			{
+				"h":          {"event"},
+				"uid":        {"2"},
+				"url":        {"http://example.com/2"},
+				"name":       {"A party"},
+				"hx-deleted": {true},
+			},
+			{
+				"uid":        {"3"},
+				"url":        {"http://example.com/3"},
+				"content":    {"no h"},
+				"hx-aliases": {"http://example.com/old-3"},
+			},
+		},
+		Revisions: []Revision{
+			{
+				UID:        "3",
+				Version:    1,
+				CreatedAt:  time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC),
+				Properties: map[string][]any{"uid": {"3"}, "content": {"first"}},
+			},
+			{
+				UID:        "3",
+				Version:    2,
+				CreatedAt:  time.Date(2020, 10, 2, 12, 0, 0, 0, time.UTC),
+				Properties: map[string][]any{"uid": {"3"}, "content": {"no h"}},
+			},
+		},
+		Mentions: []map[string][]any{
+			{
+				"hx-source": {"http://other.example.com/reply"},
+				"hx-target": {"http://example.com/1"},
+				"content":   {"nice"},
+			},
+		},
+		Redirects: []Redirect{
+			{From: "http://example.com/old", To: "http://example.com/1"},
+		},
+		Tombstones: []Tombstone{
+			{UID: "4", URL: "http://example.com/4", Deleted: time.Date(2020, 10, 3, 12, 0, 0, 0, time.UTC)},
+		},
+		Media: []MediaFile{
+			{URL: "http://media.example.com/a.jpg", Name: "a.jpg", ContentType: "image/jpeg", Size: 5},
+		},
+	}
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	for _, format := range []string{FormatMF2, FormatJF2} {
+		t.Run(format, func(t *testing.T) {
+			assert := assert.New(t)
+
+			written := testArchive()
+
+			var buf bytes.Buffer
+			assert.Nil(WriteArchive(&buf, written, format))
+			assert.Equal(testArchive(), written)
+
+			archive, err := ReadArchive(&buf)
+			assert.Nil(err)
+			assert.Equal(testArchive(), archive)
+		})
+	}
+}
+
+func TestArchiveUnknownFormat(t *testing.T) {
+	assert := assert.New(t)
+
+	var buf bytes.Buffer
+	assert.NotNil(WriteArchive(&buf, testArchive(), "what"))
+}
+
+func TestExportImport(t *testing.T) {
+	assert := assert.New(t)
+
+	newBlog := func() *Blog {
+		db, err := sql.Open("sqlite3", ":memory:")
+		assert.Nil(err)
+
+		entries, err := numbersix.For(db, "entries")
+		assert.Nil(err)
+		mentions, err := numbersix.For(db, "mentions")
+		assert.Nil(err)
+		revisions, err := newRevisionStore(db, "")
+		assert.Nil(err)
+		search, err := newSearchIndex(db, "")
+		assert.Nil(err)
+		redirects, err := newRedirectStore(db, "")
+		assert.Nil(err)
+		tombstones, err := newTombstoneStore(db, "")
+		assert.Nil(err)
+
+		return &Blog{
+			entries:    entries,
+			mentions:   mentions,
+			revisions:  revisions,
+			search:     search,
+			redirects:  redirects,
+			tombstones: tombstones,
+		}
+	}
+
+	from := newBlog()
+	assert.Nil(from.Import(testArchive()))
+
+	archive, err := from.Export()
+	assert.Nil(err)
+	assert.Len(archive.Entries, 3)
+	assert.Equal(testArchive().Redirects, archive.Redirects)
+	assert.Equal(testArchive().Tombstones, archive.Tombstones)
+	if assert.Len(archive.Mentions, 1) {
+		assert.Equal("http://other.example.com/reply", archive.Mentions[0]["hx-source"][0])
+	}
+
+	to := newBlog()
+	assert.Nil(to.Import(archive))
+
+	deleted, err := to.EntryByUID("2")
+	assert.Nil(err)
+	assert.Equal(true, deleted["hx-deleted"][0])
+
+	mentions, err := to.MentionsForEntry("http://example.com/1")
+	assert.Nil(err)
+	if assert.Len(mentions, 1) {
+		assert.Equal("http://other.example.com/reply", mentions[0].Subject)
+		assert.Equal("nice", mentions[0].Properties["content"][0])
+	}
+
+	noH, err := to.EntryByUID("3")
+	assert.Nil(err)
+	assert.Nil(noH["h"])
+	assert.Equal("http://example.com/old-3", noH["hx-aliases"][0])
+
+	revisions, err := to.Revisions("3")
+	assert.Nil(err)
+	if assert.Len(revisions, 2) {
+		assert.Equal("first", revisions[0].Properties["content"][0])
+	}
+	revisions, err = to.Revisions("1")
+	assert.Nil(err)
+	assert.Len(revisions, 1)
+
+	redirect, err := to.redirects.Get("http://example.com/old")
+	assert.Nil(err)
+	assert.Equal("http://example.com/1", redirect)
+
+	tombstone, err := to.tombstones.ForUID("4")
+	assert.Nil(err)
+	assert.Equal("http://example.com/4", tombstone.URL)
+
+	found, err := to.Search("hey")
+	assert.Nil(err)
+	assert.Len(found, 1)
+
+	assert.NotNil(to.Import(archive))
+}
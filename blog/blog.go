@@ -2,18 +2,21 @@ package blog
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"log/slog"
 	"net/http"
 	"net/url"
+	"strings"
+	"sync"
 	"time"
 
-	"github.com/gorilla/feeds"
 	"hawx.me/code/numbersix"
 	"hawx.me/code/route"
+	"hawx.me/code/tally-ho/internal/mfutil"
 	"hawx.me/code/tally-ho/internal/page"
+	"hawx.me/code/tally-ho/internal/posttype"
 )
 
 type Config struct {
@@ -21,19 +24,47 @@ type Config struct {
 	BaseURL  *url.URL
 	MediaURL *url.URL
 	HubURL   string
+
+	// PageSize is the number of entries, or mentions, shown on each page of a
+	// list. If not set 25 are shown.
+	PageSize int
+
+	// MediaDir is the directory that media is written to, so that it can be
+	// removed when the entry it was uploaded for is purged.
+	MediaDir string
+
+	// TablePrefix is added to the names of the tables the blog uses, so that
+	// more than one blog can share a database.
+	TablePrefix string
+
+	// IsOwner reports whether a request has been authenticated as Me. It is used
+	// to allow previewing entries that are not yet published.
+	IsOwner func(r *http.Request) bool
 }
 
 type Blog struct {
 	local         bool
 	config        Config
 	pageCtx       page.Context
-	closer        io.Closer
 	entries       *numbersix.DB
 	mentions      *numbersix.DB
+	revisions     *revisionStore
+	search        *searchIndex
+	redirects     *redirectStore
+	tombstones    *tombstoneStore
 	syndicators   map[string]Syndicator
 	citeResolvers []CiteResolver
 	cardResolvers []CardResolver
 	hubPublisher  HubPublisher
+
+	// createMu ensures only one entry is claiming a url at a time
+	createMu sync.Mutex
+	// buildDir is where pages are written when they change, if set
+	buildDir string
+	// buildMu ensures only one set of pages is being written at a time
+	buildMu sync.Mutex
+	// done is closed to stop releasing scheduled entries
+	done chan struct{}
 }
 
 func New(
@@ -44,12 +75,32 @@ func New(
 	hubPublisher HubPublisher,
 	silos []any,
 ) (*Blog, error) {
-	entries, err := numbersix.For(db, "entries")
+	entries, err := numbersix.For(db, config.TablePrefix+"entries")
+	if err != nil {
+		return nil, err
+	}
+
+	mentions, err := numbersix.For(db, config.TablePrefix+"mentions")
+	if err != nil {
+		return nil, err
+	}
+
+	revisions, err := newRevisionStore(db, config.TablePrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	search, err := newSearchIndex(db, config.TablePrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	redirects, err := newRedirectStore(db, config.TablePrefix)
 	if err != nil {
 		return nil, err
 	}
 
-	mentions, err := numbersix.For(db, "mentions")
+	tombstones, err := newTombstoneStore(db, config.TablePrefix)
 	if err != nil {
 		return nil, err
 	}
@@ -77,22 +128,31 @@ func New(
 		logger.Info("running in local mode")
 	}
 
-	return &Blog{
+	b := &Blog{
 		local:         local,
 		config:        config,
 		pageCtx:       pageCtx,
-		closer:        db,
 		entries:       entries,
 		mentions:      mentions,
+		revisions:     revisions,
+		search:        search,
+		redirects:     redirects,
+		tombstones:    tombstones,
 		syndicators:   syndicators,
 		citeResolvers: citeResolvers,
 		cardResolvers: cardResolvers,
 		hubPublisher:  hubPublisher,
-	}, nil
+		done:          make(chan struct{}),
+	}
+
+	return b, nil
 }
 
+// Close stops the work the blog does in the background. The db it was given is
+// left open, as it may be shared with other blogs.
 func (b *Blog) Close() error {
-	return b.closer.Close()
+	close(b.done)
+	return nil
 }
 
 func (b *Blog) BaseURL() string {
@@ -106,11 +166,14 @@ func (b *Blog) absoluteURL(p string) string {
 }
 
 func (b *Blog) Handler() http.Handler {
-	baseURL := b.config.BaseURL
+	return b.handler(false)
+}
+
+// handler returns the http.Handler for the blog. When static is true the pages
+// of lists link to each other with a path, rather than a query, so that they can
+// be written to files.
+func (b *Blog) handler(static bool) http.Handler {
 	indexURL := b.absoluteURL("")
-	feedAtomURL := b.absoluteURL("feed/atom")
-	feedJsonfeedURL := b.absoluteURL("feed/jsonfeed")
-	feedRssURL := b.absoluteURL("feed/rss")
 
 	mux := route.New()
 	mux.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
@@ -124,137 +187,224 @@ func (b *Blog) Handler() http.Handler {
 		http.Error(w, "something unexpected happened", http.StatusInternalServerError)
 	}
 
-	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) error {
-		showLatest := true
+	// pageURL returns the url of the page of the list at path with the cursor,
+	// or nothing if there is no cursor
+	pageURL := func(path, direction, cursor string) string {
+		if cursor == "" {
+			return ""
+		}
 
-		before, err := time.Parse(time.RFC3339, r.FormValue("before"))
-		if err != nil {
-			showLatest = false
-			before = time.Now().UTC()
+		if static {
+			return b.absoluteURL(path + direction + "/" + url.PathEscape(cursor))
 		}
 
-		posts, err := b.Before(before)
+		return b.absoluteURL(path) + "?" + direction + "=" + url.QueryEscape(cursor)
+	}
+
+	// list renders the page of entries matching query for the list at path
+	list := func(w http.ResponseWriter, r *http.Request, path string, query func() *numbersix.Query, data page.ListData) error {
+		before, after := listCursor(r)
+
+		posts, err := b.listPage(b.entries, query, before, after)
 		if err != nil {
 			return err
 		}
 
-		olderThan := ""
-		if len(posts) == 25 {
-			olderThan = posts[len(posts)-1].Properties["published"][0].(string)
-		} else if len(posts) == 0 {
-			olderThan = "NOMORE"
+		data.GroupedPosts = groupLikes(b.pageCtx, b.groupedWithAuthors(posts.Items))
+		data.NewerURL = pageURL(path, "after", posts.Newer)
+		data.OlderURL = pageURL(path, "before", posts.Older)
+		data.ShowLatest = before != "" || after != ""
+
+		addPageLinks(w, data.NewerURL, data.OlderURL)
+
+		if _, err := page.List(b.pageCtx, data).WriteTo(w); err != nil {
+			return fmt.Errorf("render: %w", err)
 		}
 
+		return nil
+	}
+
+	index := func(w http.ResponseWriter, r *http.Request) error {
 		w.Header().Add("Link", `<`+indexURL+`>; rel="self"`)
 		w.Header().Add("Link", `<`+b.config.HubURL+`>; rel="hub"`)
 
-		if _, err := page.List(b.pageCtx, page.ListData{
-			GroupedPosts: groupLikes(b.pageCtx, posts),
-			OlderThan:    olderThan,
-			ShowLatest:   showLatest,
-		}).WriteTo(w); err != nil {
+		return list(w, r, "", listed, page.ListData{})
+	}
+
+	mux.HandleFunc("/", index)
+	mux.HandleFunc("/before/:before", index)
+	mux.HandleFunc("/after/:after", index)
+
+	kind := func(w http.ResponseWriter, r *http.Request) error {
+		kind := route.Vars(r)["kind"]
+
+		if _, ok := posttype.Get(kind); !ok {
+			return fmt.Errorf("kind %s: %w", kind, ErrNotFound)
+		}
+
+		return list(w, r, "kind/"+kind+"/", listedKind(kind), page.ListData{Kind: kind})
+	}
+
+	mux.HandleFunc("/kind/:kind", kind)
+	mux.HandleFunc("/kind/:kind/before/:before", kind)
+	mux.HandleFunc("/kind/:kind/after/:after", kind)
+
+	category := func(w http.ResponseWriter, r *http.Request) error {
+		category := route.Vars(r)["category"]
+		if err := b.hasListedCategory(category); err != nil {
 			return err
 		}
 
-		return nil
-	})
+		return list(w, r, "category/"+url.PathEscape(category)+"/", listedCategory(category), page.ListData{Category: category})
+	}
 
-	mux.HandleFunc("/kind/:kind", func(w http.ResponseWriter, r *http.Request) error {
-		vars := route.Vars(r)
+	mux.HandleFunc("/category/:category", category)
+	mux.HandleFunc("/category/:category/before/:before", category)
+	mux.HandleFunc("/category/:category/after/:after", category)
 
-		showLatest := true
+	// feedURL returns the url of the feed at path in format, or of its archive
+	// for the month when one is given
+	feedURL := func(path, format, month string) string {
+		if month == "" {
+			return b.absoluteURL(path + format)
+		}
 
-		before, err := time.Parse(time.RFC3339, r.FormValue("before"))
-		if err != nil {
-			showLatest = false
-			before = time.Now().UTC()
+		return b.absoluteURL(path + strings.Replace(month, "-", "/", 1) + "/" + format)
+	}
+
+	// feed writes the document of the feed for the list at path in format, of
+	// entries matching query
+	feed := func(w http.ResponseWriter, r *http.Request, path, format, title string, query func() *numbersix.Query) error {
+		var month string
+		if vars := route.Vars(r); vars["year"] != "" {
+			period, ok := archivePeriod(vars)
+			if !ok {
+				return fmt.Errorf("feed archive %s: %w", r.URL.Path, ErrNotFound)
+			}
+			month = period
 		}
 
-		posts, err := b.KindBefore(vars["kind"], before)
+		f, err := b.feed(path, title, query, month)
 		if err != nil {
-			return err
+			return fmt.Errorf("get feed: %w", err)
 		}
 
-		olderThan := ""
-		if len(posts) == 25 {
-			olderThan = posts[len(posts)-1].Properties["published"][0].(string)
-		} else if len(posts) == 0 {
-			olderThan = "NOMORE"
+		feedPath := path + "feed/"
+
+		links := feedLinks{
+			Self:    feedURL(feedPath, format, month),
+			Current: feedURL(feedPath, format, ""),
+		}
+		if f.PrevArchive != "" {
+			links.PrevArchive = feedURL(feedPath, format, f.PrevArchive)
+		}
+		if f.NextArchive != "" {
+			links.NextArchive = feedURL(feedPath, format, f.NextArchive)
+		}
+		if month == "" {
+			links.Hub = b.config.HubURL
 		}
 
-		if _, err := page.List(b.pageCtx, page.ListData{
-			GroupedPosts: groupLikes(b.pageCtx, posts),
-			OlderThan:    olderThan,
-			ShowLatest:   showLatest,
-			Kind:         vars["kind"],
-		}).WriteTo(w); err != nil {
-			return err
+		return writeFeed(w, format, f, links)
+	}
+
+	for _, format := range feedFormats {
+		index := func(w http.ResponseWriter, r *http.Request) error {
+			return feed(w, r, "", format, b.pageCtx.Name+" posts", listed)
 		}
 
-		return nil
-	})
+		mux.HandleFunc("/feed/"+format, index)
+		mux.HandleFunc("/feed/:year/:month/"+format, index)
 
-	mux.HandleFunc("/category/:category", func(w http.ResponseWriter, r *http.Request) error {
-		vars := route.Vars(r)
+		kind := func(w http.ResponseWriter, r *http.Request) error {
+			kind := route.Vars(r)["kind"]
 
-		showLatest := true
+			t, ok := posttype.Get(kind)
+			if !ok {
+				return fmt.Errorf("kind %s: %w", kind, ErrNotFound)
+			}
 
-		before, err := time.Parse(time.RFC3339, r.FormValue("before"))
-		if err != nil {
-			showLatest = false
-			before = time.Now().UTC()
+			return feed(w, r, "kind/"+kind+"/", format,
+				b.pageCtx.Name+" "+strings.ToLower(t.Name)+" posts",
+				listedKind(kind))
 		}
 
-		posts, err := b.CategoryBefore(vars["category"], before)
+		mux.HandleFunc("/kind/:kind/feed/"+format, kind)
+		mux.HandleFunc("/kind/:kind/feed/:year/:month/"+format, kind)
+
+		category := func(w http.ResponseWriter, r *http.Request) error {
+			category := route.Vars(r)["category"]
+			if err := b.hasListedCategory(category); err != nil {
+				return err
+			}
+
+			return feed(w, r, "category/"+url.PathEscape(category)+"/", format,
+				b.pageCtx.Name+" posts in "+category,
+				listedCategory(category))
+		}
+
+		mux.HandleFunc("/category/:category/feed/"+format, category)
+		mux.HandleFunc("/category/:category/feed/:year/:month/"+format, category)
+	}
+
+	mux.HandleFunc("/entry/:id", func(w http.ResponseWriter, r *http.Request) error {
+		vars := route.Vars(r)
+
+		entry, err := b.EntryByUID(vars["id"])
 		if err != nil {
-			return err
+			if tombstone, err := b.tombstones.ForUID(vars["id"]); err == nil {
+				return b.renderTombstone(w, tombstone.URL, tombstone.Deleted)
+			}
+
+			return fmt.Errorf("entry by uid: %w", err)
 		}
 
-		olderThan := ""
-		if len(posts) == 25 {
-			olderThan = posts[len(posts)-1].Properties["published"][0].(string)
-		} else if len(posts) == 0 {
-			olderThan = "NOMORE"
+		if !b.canView(r, entry) {
+			return fmt.Errorf("entry is not published: %w", ErrNotFound)
 		}
 
-		if _, err := page.List(b.pageCtx, page.ListData{
-			GroupedPosts: groupLikes(b.pageCtx, posts),
-			OlderThan:    olderThan,
-			ShowLatest:   showLatest,
-			Kind:         "",
-			Category:     vars["category"],
-		}).WriteTo(w); err != nil {
-			return fmt.Errorf("render: %w", err)
+		// entries created with a slug are still reachable by their uid, but
+		// should be linked to by their permalink
+		if location, ok := entry["url"][0].(string); ok && location != b.absoluteURL("entry/"+vars["id"]) {
+			if r.URL.RawQuery != "" {
+				location += "?" + r.URL.RawQuery
+			}
+
+			http.Redirect(w, r, location, http.StatusMovedPermanently)
+			return nil
 		}
 
-		return nil
+		return b.renderEntry(w, r, entry)
 	})
 
-	mux.HandleFunc("/entry/:id", func(w http.ResponseWriter, r *http.Request) error {
+	mux.HandleFunc("/entry/:id/history", func(w http.ResponseWriter, r *http.Request) error {
 		vars := route.Vars(r)
 
 		entry, err := b.EntryByUID(vars["id"])
 		if err != nil {
+			if tombstone, err := b.tombstones.ForUID(vars["id"]); err == nil {
+				return b.renderTombstone(w, tombstone.URL, tombstone.Deleted)
+			}
+
 			return fmt.Errorf("entry by uid: %w", err)
 		}
 
+		if !b.canView(r, entry) {
+			return fmt.Errorf("entry is not published: %w", ErrNotFound)
+		}
+
 		if deleted, ok := entry["hx-deleted"]; ok && len(deleted) > 0 {
-			http.Error(w, "gone", http.StatusGone)
-			return nil
+			return b.renderTombstone(w, entry["url"][0].(string), deletedAt(entry))
 		}
 
-		mentions, err := b.MentionsForEntry(baseURL.ResolveReference(r.URL).String())
+		revisions, err := b.Revisions(vars["id"])
 		if err != nil {
-			return fmt.Errorf("mentions for entry: %w", err)
+			return fmt.Errorf("revisions: %w", err)
 		}
 
-		if _, err := page.Post(b.pageCtx, page.PostData{
-			Entry: entry,
-			Posts: GroupedPosts{
-				Type: "entry",
-				Meta: entry,
-			},
-			Mentions: mentions,
+		if _, err := page.History(b.pageCtx, page.HistoryData{
+			Entry:     entry,
+			Revisions: history(revisions),
 		}).WriteTo(w); err != nil {
 			return fmt.Errorf("render: %w", err)
 		}
@@ -262,6 +412,23 @@ func (b *Blog) Handler() http.Handler {
 		return nil
 	})
 
+	mux.HandleFunc("/:year/:month/:day/:slug", func(w http.ResponseWriter, r *http.Request) error {
+		vars := route.Vars(r)
+
+		location := b.absoluteURL(vars["year"] + "/" + vars["month"] + "/" + vars["day"] + "/" + vars["slug"])
+
+		entry, err := b.Entry(location)
+		if err != nil {
+			if tombstone, err := b.tombstones.ForURL(location); err == nil {
+				return b.renderTombstone(w, tombstone.URL, tombstone.Deleted)
+			}
+
+			return fmt.Errorf("entry: %w", err)
+		}
+
+		return b.renderEntry(w, r, entry)
+	})
+
 	mux.HandleFunc("/likes/:ymd", func(w http.ResponseWriter, r *http.Request) error {
 		ymd := route.Vars(r)["ymd"]
 
@@ -281,132 +448,189 @@ func (b *Blog) Handler() http.Handler {
 	})
 
 	mux.HandleFunc("/mentions", func(w http.ResponseWriter, r *http.Request) error {
-		showLatest := true
-
-		before, err := time.Parse(time.RFC3339, r.FormValue("before"))
-		if err != nil {
-			showLatest = false
-			before = time.Now().UTC()
-		}
+		before, after := listCursor(r)
 
-		mentions, err := b.MentionsBefore(before, 25)
+		mentions, err := b.listPage(b.mentions, numbersix.All, before, after)
 		if err != nil {
 			return err
 		}
 
-		olderThan := ""
-		if len(mentions) == 25 {
-			olderThan = mentions[len(mentions)-1].Properties["published"][0].(string)
-		} else if len(mentions) == 0 {
-			olderThan = "NOMORE"
+		// mentions are not written as static files, so always use parameters
+		mentionsURL := b.absoluteURL("mentions")
+		data := page.MentionsData{
+			Title:      "mentions",
+			Items:      mentions.Items,
+			ShowLatest: before != "" || after != "",
+		}
+		if mentions.Newer != "" {
+			data.NewerURL = mentionsURL + "?after=" + url.QueryEscape(mentions.Newer)
+		}
+		if mentions.Older != "" {
+			data.OlderURL = mentionsURL + "?before=" + url.QueryEscape(mentions.Older)
 		}
 
-		if _, err := page.Mentions(b.pageCtx, page.MentionsData{
-			Title:      "mentions",
-			Items:      mentions,
-			OlderThan:  olderThan,
-			ShowLatest: showLatest,
-		}).WriteTo(w); err != nil {
+		addPageLinks(w, data.NewerURL, data.OlderURL)
+
+		if _, err := page.Mentions(b.pageCtx, data).WriteTo(w); err != nil {
 			return fmt.Errorf("render: %w", err)
 		}
 
 		return nil
 	})
 
-	mux.HandleFunc("/feed/rss", func(w http.ResponseWriter, r *http.Request) error {
-		f, err := b.feed()
+	mux.HandleFunc("/search", func(w http.ResponseWriter, r *http.Request) error {
+		query := r.FormValue("q")
+
+		posts, err := b.Search(query)
 		if err != nil {
-			return fmt.Errorf("get feed: %w", err)
+			return fmt.Errorf("search: %w", err)
 		}
 
-		rss, err := f.ToRss()
-		if err != nil {
-			return fmt.Errorf("to rss: %w", err)
+		if _, err := page.Search(b.pageCtx, page.SearchData{
+			Query:        query,
+			GroupedPosts: groupLikes(b.pageCtx, posts),
+		}).WriteTo(w); err != nil {
+			return fmt.Errorf("render: %w", err)
 		}
 
-		w.Header().Add("Link", `<`+feedRssURL+`>; rel="self"`)
-		w.Header().Add("Link", `<`+b.config.HubURL+`>; rel="hub"`)
-		w.Header().Set("Content-Type", "application/rss+xml")
-		io.WriteString(w, rss)
 		return nil
 	})
 
-	mux.HandleFunc("/feed/atom", func(w http.ResponseWriter, r *http.Request) error {
-		f, err := b.feed()
+	mux.HandleFunc("/search/json", func(w http.ResponseWriter, r *http.Request) error {
+		posts, err := b.Search(r.FormValue("q"))
 		if err != nil {
-			return fmt.Errorf("get feed: %w", err)
+			return fmt.Errorf("search: %w", err)
 		}
 
-		atom, err := f.ToAtom()
-		if err != nil {
-			return fmt.Errorf("to atom: %w", err)
+		items := make([]map[string]any, len(posts))
+		for i, post := range posts {
+			items[i] = publicMicroformat(post.Properties)
 		}
 
-		w.Header().Add("Link", `<`+feedAtomURL+`>; rel="self"`)
-		w.Header().Add("Link", `<`+b.config.HubURL+`>; rel="hub"`)
-		w.Header().Set("Content-Type", "application/atom+xml")
-		io.WriteString(w, atom)
-		return nil
+		w.Header().Set("Content-Type", "application/json")
+		return json.NewEncoder(w).Encode(map[string]any{"items": items})
 	})
 
-	mux.HandleFunc("/feed/jsonfeed", func(w http.ResponseWriter, r *http.Request) error {
-		f, err := b.feed()
+	// archives are matched last, so that they do not hide other pages
+	archive := func(w http.ResponseWriter, r *http.Request) error {
+		period, ok := archivePeriod(route.Vars(r))
+		if !ok {
+			return fmt.Errorf("archive %s: %w", r.URL.Path, ErrNotFound)
+		}
+
+		posts, err := b.EntriesOn(period)
 		if err != nil {
-			return fmt.Errorf("get feed: %w", err)
+			return err
 		}
 
-		json, err := f.ToJSON()
+		counts, err := b.PublishedCounts()
 		if err != nil {
-			return fmt.Errorf("to json: %w", err)
+			return err
+		}
+
+		if _, err := page.Archive(b.pageCtx, page.ArchiveData{
+			Period:       period,
+			Counts:       counts,
+			GroupedPosts: groupLikes(b.pageCtx, posts),
+		}).WriteTo(w); err != nil {
+			return fmt.Errorf("render: %w", err)
 		}
 
-		w.Header().Add("Link", `<`+feedJsonfeedURL+`>; rel="self"`)
-		w.Header().Add("Link", `<`+b.config.HubURL+`>; rel="hub"`)
-		w.Header().Set("Content-Type", "application/json")
-		io.WriteString(w, json)
 		return nil
-	})
+	}
 
-	// route.Handle("/:year/:month/:date/:slug")
+	mux.HandleFunc("/:year", archive)
+	mux.HandleFunc("/:year/:month", archive)
+	mux.HandleFunc("/:year/:month/:day", archive)
 
-	return mux
+	return b.redirecting(mux)
 }
 
-func (b *Blog) feed() (*feeds.Feed, error) {
-	feed := &feeds.Feed{
-		Title:   b.pageCtx.Name + " posts",
-		Link:    &feeds.Link{Href: b.config.BaseURL.String()},
-		Author:  &feeds.Author{Name: b.pageCtx.Name},
-		Created: time.Now(),
+func (b *Blog) renderEntry(w http.ResponseWriter, r *http.Request, entry map[string][]any) error {
+	if !b.canView(r, entry) {
+		return fmt.Errorf("entry is not published: %w", ErrNotFound)
+	}
+
+	if deleted, ok := entry["hx-deleted"]; ok && len(deleted) > 0 {
+		return b.renderTombstone(w, entry["url"][0].(string), deletedAt(entry))
 	}
 
-	posts, err := b.Before(time.Now().UTC())
+	mentions, err := b.MentionsForEntry(entry["url"][0].(string))
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("mentions for entry: %w", err)
+	}
+
+	if _, err := page.Post(b.pageCtx, page.PostData{
+		Entry: entry,
+		Posts: GroupedPosts{
+			Type: "entry",
+			Meta: entry,
+		},
+		Mentions: mentions,
+	}).WriteTo(w); err != nil {
+		return fmt.Errorf("render: %w", err)
 	}
 
-	for _, post := range posts {
-		relURL, _ := url.Parse(post.Properties["url"][0].(string))
-		absURL := b.config.BaseURL.ResolveReference(relURL)
+	return nil
+}
+
+// hasListedCategory returns ErrNotFound if no listed entries are in category,
+// so that categories only used by hidden entries are not shown.
+func (b *Blog) hasListedCategory(category string) error {
+	triples, err := b.entries.List(listedCategory(category)().Limit(1))
+	if err != nil {
+		return fmt.Errorf("category %s: %w", category, err)
+	}
+	if len(triples) == 0 {
+		return fmt.Errorf("category %s: %w", category, ErrNotFound)
+	}
 
-		createdAt, _ := time.Parse(time.RFC3339, post.Properties["published"][0].(string))
+	return nil
+}
 
-		feed.Items = append(feed.Items, &feeds.Item{
-			Title:       page.DecideTitle(post.Properties),
-			Link:        &feeds.Link{Href: absURL.String()},
-			Description: "",
-			Created:     createdAt,
-		})
+// archivePeriod returns the period that an archive page is for, as 2006,
+// 2006-01 or 2006-01-02, if the route variables are a valid date.
+func archivePeriod(vars map[string]string) (string, bool) {
+	period, layout := vars["year"], "2006"
+	if month, ok := vars["month"]; ok {
+		period, layout = period+"-"+month, layout+"-01"
+	}
+	if day, ok := vars["day"]; ok {
+		period, layout = period+"-"+day, layout+"-02"
 	}
 
-	return feed, nil
+	_, err := time.Parse(layout, period)
+	return period, err == nil
 }
 
-type pageListCtx struct {
-	Title        string
-	GroupedPosts []GroupedPosts
-	OlderThan    string
-	ShowLatest   bool
-	Kind         string
-	Category     string
+// addPageLinks adds Link headers relating a page of a list to the pages either
+// side of it.
+func addPageLinks(w http.ResponseWriter, newerURL, olderURL string) {
+	if newerURL != "" {
+		w.Header().Add("Link", `<`+newerURL+`>; rel="prev"`)
+	}
+	if olderURL != "" {
+		w.Header().Add("Link", `<`+olderURL+`>; rel="next"`)
+	}
+}
+
+// publicMicroformat returns the entry as microformats2 JSON, leaving out the
+// properties that are only used internally.
+func publicMicroformat(data map[string][]any) map[string]any {
+	h, ok := mfutil.Get(data, "h").(string)
+	if !ok {
+		h = "entry"
+	}
+
+	properties := map[string][]any{}
+	for key, value := range data {
+		if key != "h" && !strings.HasPrefix(key, "hx-") && !strings.HasPrefix(key, "mp-") {
+			properties[key] = value
+		}
+	}
+
+	return map[string]any{
+		"type":       []any{"h-" + h},
+		"properties": properties,
+	}
 }
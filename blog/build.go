@@ -0,0 +1,426 @@
+package blog
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"hawx.me/code/numbersix"
+	"hawx.me/code/tally-ho/internal/mfutil"
+	"hawx.me/code/tally-ho/internal/posttype"
+)
+
+// Build writes every page of the blog that can be seen without signing in to
+// dir, so that it can be hosted as static files. Each page is written to the
+// index.html file of a directory matching its path, except for feeds which are
+// written to a file at their path.
+func (b *Blog) Build(dir string) error {
+	var paths []string
+
+	lists, err := b.listPaths()
+	if err != nil {
+		return err
+	}
+	paths = append(paths, lists...)
+
+	// deleted entries are included, so that their tombstone is written
+	triples, err := b.entries.List(
+		numbersix.
+			Without("hx-scheduled").
+			Without("hx-draft").
+			Without("hx-private"),
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, group := range numbersix.Grouped(triples) {
+		paths = append(paths, b.entryPaths(group.Properties)...)
+	}
+
+	tombstones, err := b.tombstones.List()
+	if err != nil {
+		return err
+	}
+	for _, tombstone := range tombstones {
+		paths = append(paths, "/entry/"+tombstone.UID)
+		if path, ok := b.localPath(tombstone.URL); ok {
+			paths = append(paths, path)
+		}
+	}
+
+	counts, err := b.PublishedCounts()
+	if err != nil {
+		return err
+	}
+	for period := range counts {
+		paths = append(paths, "/"+strings.ReplaceAll(period, "-", "/"))
+	}
+
+	redirects, err := b.Redirects()
+	if err != nil {
+		return err
+	}
+	for _, redirect := range redirects {
+		if path, ok := b.localPath(redirect.From); ok {
+			paths = append(paths, path)
+		}
+	}
+
+	return b.writePages(dir, uniquePaths(paths))
+}
+
+// BuildOnChange makes the blog write the pages that are affected by a change to
+// an entry to dir, after each change is made. It expects that Build has already
+// been used to write all of the pages.
+func (b *Blog) BuildOnChange(dir string) {
+	b.buildDir = dir
+}
+
+// rebuild writes the pages that show any of the versions of an entry, if
+// BuildOnChange has been used.
+func (b *Blog) rebuild(datas ...map[string][]any) {
+	if b.buildDir == "" {
+		return
+	}
+
+	go func() {
+		b.buildMu.Lock()
+		defer b.buildMu.Unlock()
+
+		paths := []string{"/"}
+
+		for _, data := range datas {
+			paths = append(paths, b.entryPaths(data)...)
+
+			if kind, ok := mfutil.Get(data, "hx-kind").(string); ok {
+				paths = append(paths, "/kind/"+kind)
+			}
+			for _, category := range searchStrings(data["category"]) {
+				paths = append(paths, "/category/"+url.PathEscape(category))
+			}
+		}
+
+		var pages []string
+		for _, path := range uniquePaths(paths) {
+			if path == "/" || strings.HasPrefix(path, "/kind/") || strings.HasPrefix(path, "/category/") {
+				chain, err := b.listChain(path)
+				if err != nil {
+					slog.Error("rebuild", slog.String("path", path), slog.Any("err", err))
+					continue
+				}
+				feeds, err := b.feedPaths(path)
+				if err != nil {
+					slog.Error("rebuild", slog.String("path", path), slog.Any("err", err))
+					continue
+				}
+				pages = append(pages, chain...)
+				pages = append(pages, feeds...)
+				continue
+			}
+
+			pages = append(pages, path)
+		}
+
+		if err := b.writePages(b.buildDir, pages); err != nil {
+			slog.Error("rebuild", slog.Any("err", err))
+		}
+	}()
+}
+
+// rebuildRedirect writes the page for a url that has been redirected, or
+// removes it when it no longer is, if BuildOnChange has been used.
+func (b *Blog) rebuildRedirect(from string) {
+	path, ok := b.localPath(from)
+	if b.buildDir == "" || !ok {
+		return
+	}
+
+	go func() {
+		b.buildMu.Lock()
+		defer b.buildMu.Unlock()
+
+		if err := b.writePages(b.buildDir, []string{path}); err != nil {
+			slog.Error("rebuild", slog.Any("err", err))
+		}
+	}()
+}
+
+// listPaths returns the paths of every page of the lists of entries.
+func (b *Blog) listPaths() ([]string, error) {
+	bases := []string{"/"}
+
+	for _, t := range posttype.Types {
+		bases = append(bases, "/kind/"+t.Type)
+	}
+
+	categories, err := b.categories(listed().Has("category"))
+	if err != nil {
+		return nil, err
+	}
+	for _, category := range categories {
+		bases = append(bases, "/category/"+url.PathEscape(category))
+	}
+
+	var paths []string
+	for _, base := range bases {
+		chain, err := b.listChain(base)
+		if err != nil {
+			return nil, err
+		}
+
+		feeds, err := b.feedPaths(base)
+		if err != nil {
+			return nil, err
+		}
+
+		paths = append(paths, chain...)
+		paths = append(paths, feeds...)
+	}
+
+	return paths, nil
+}
+
+// listQuery returns the query for the entries in the list at base.
+func listQuery(base string) (func() *numbersix.Query, error) {
+	switch {
+	case strings.HasPrefix(base, "/kind/"):
+		return listedKind(strings.TrimPrefix(base, "/kind/")), nil
+	case strings.HasPrefix(base, "/category/"):
+		category, err := url.PathUnescape(strings.TrimPrefix(base, "/category/"))
+		if err != nil {
+			return nil, err
+		}
+		return listedCategory(category), nil
+	}
+
+	return listed, nil
+}
+
+// listChain returns the path of the list at base, followed by the paths of each
+// page of older entries that it links to, and of the pages of newer entries that
+// those link back to.
+func (b *Blog) listChain(base string) ([]string, error) {
+	query, err := listQuery(base)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := strings.TrimSuffix(base, "/") + "/"
+	paths := []string{base}
+	before := ""
+
+	for {
+		p, err := b.listPage(b.entries, query, before, "")
+		if err != nil {
+			return nil, err
+		}
+
+		if p.Newer != "" {
+			paths = append(paths, prefix+"after/"+url.PathEscape(p.Newer))
+		}
+
+		if p.Older == "" {
+			return paths, nil
+		}
+
+		before = p.Older
+		paths = append(paths, prefix+"before/"+url.PathEscape(before))
+	}
+}
+
+// feedPaths returns the paths of the feed of the list at base, followed by the
+// paths of each archive of it that are linked back to from there.
+func (b *Blog) feedPaths(base string) ([]string, error) {
+	query, err := listQuery(base)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := strings.TrimSuffix(base, "/") + "/feed/"
+	months := []string{""}
+
+	month := time.Now().UTC().Format(feedMonth)
+	for {
+		if month, err = b.prevArchive(query, month); err != nil {
+			return nil, err
+		}
+		if month == "" {
+			break
+		}
+		months = append(months, month)
+	}
+
+	var paths []string
+	for _, month := range months {
+		if month != "" {
+			month = strings.Replace(month, "-", "/", 1) + "/"
+		}
+
+		for _, format := range feedFormats {
+			paths = append(paths, prefix+month+format)
+		}
+	}
+
+	return paths, nil
+}
+
+// isFeedPath reports whether path is for a page of a feed, which is written to a
+// file rather than a directory.
+func isFeedPath(path string) bool {
+	for _, prefix := range []string{"/kind/", "/category/"} {
+		if rest, ok := strings.CutPrefix(path, prefix); ok {
+			_, path, _ = strings.Cut(rest, "/")
+			path = "/" + path
+		}
+	}
+
+	return strings.HasPrefix(path, "/feed/")
+}
+
+// entryPaths returns the paths of the pages that show the entry on its own, or
+// with others published on the same day, and those of its aliases.
+func (b *Blog) entryPaths(data map[string][]any) []string {
+	var paths []string
+
+	if uid, ok := mfutil.Get(data, "uid").(string); ok {
+		paths = append(paths, "/entry/"+uid)
+
+		// entries that have been updated link to their history
+		if mfutil.Has(data, "updated") {
+			paths = append(paths, "/entry/"+uid+"/history")
+		}
+	}
+
+	if location, ok := mfutil.Get(data, "url").(string); ok {
+		if path, ok := b.localPath(location); ok {
+			paths = append(paths, path)
+		}
+	}
+
+	for _, alias := range searchStrings(data["hx-aliases"]) {
+		if path, ok := b.localPath(alias); ok {
+			paths = append(paths, path)
+		}
+	}
+
+	if published, ok := mfutil.Get(data, "published").(string); ok && len(published) >= len(time.DateOnly) {
+		paths = append(paths,
+			"/"+published[:4],
+			"/"+published[:4]+"/"+published[5:7],
+			"/"+published[:4]+"/"+published[5:7]+"/"+published[8:10],
+		)
+
+		if mfutil.Get(data, "hx-kind") == "like" {
+			paths = append(paths, "/likes/"+published[:10])
+		}
+	}
+
+	return paths
+}
+
+// localPath returns the path of location, if it is a url of the blog.
+func (b *Blog) localPath(location string) (string, bool) {
+	path, ok := strings.CutPrefix(location, b.absoluteURL(""))
+	if !ok {
+		return "", false
+	}
+
+	return "/" + path, true
+}
+
+// writePages renders each path with the handler and writes the result to dir.
+// The paths should be escaped.
+// Pages that are not found have any file previously written removed, but those
+// that are gone are written so that their tombstone is still shown.
+func (b *Blog) writePages(dir string, paths []string) error {
+	handler := b.handler(true)
+
+	for _, path := range paths {
+		// paths are escaped, as they would be in a link, but the file is named
+		// for the path a server will look for once it has unescaped the request
+		r, err := http.NewRequest("GET", path, nil)
+		if err != nil || strings.Contains(r.URL.Path, "..") {
+			continue
+		}
+
+		file := filepath.Join(dir, filepath.FromSlash(r.URL.Path))
+		if !isFeedPath(path) {
+			file = filepath.Join(file, "index.html")
+		}
+
+		w := &pageWriter{header: http.Header{}, code: http.StatusOK}
+		handler.ServeHTTP(w, r)
+
+		body := w.body.Bytes()
+
+		switch w.code {
+		case http.StatusOK, http.StatusGone:
+		case http.StatusMovedPermanently, http.StatusFound:
+			body = []byte(redirectPage(w.header.Get("Location")))
+		case http.StatusNotFound:
+			if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			continue
+		default:
+			return fmt.Errorf("build %s: status %d", path, w.code)
+		}
+
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(file, body, 0o644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// pageWriter keeps the response to a request for a page, so that it can be
+// written to a file.
+type pageWriter struct {
+	header http.Header
+	code   int
+	body   bytes.Buffer
+}
+
+func (w *pageWriter) Header() http.Header         { return w.header }
+func (w *pageWriter) Write(p []byte) (int, error) { return w.body.Write(p) }
+func (w *pageWriter) WriteHeader(code int)        { w.code = code }
+
+// redirectPage returns a page that sends the browser on to location, as static
+// files can not respond with a redirect.
+func redirectPage(location string) string {
+	return fmt.Sprintf(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<link rel="canonical" href="%[1]s">
+<meta http-equiv="refresh" content="0; url=%[1]s">
+</head>
+</html>
+`, html.EscapeString(location))
+}
+
+func uniquePaths(paths []string) []string {
+	seen := map[string]struct{}{}
+
+	var unique []string
+	for _, path := range paths {
+		if _, ok := seen[path]; !ok {
+			seen[path] = struct{}{}
+			unique = append(unique, path)
+		}
+	}
+
+	return unique
+}
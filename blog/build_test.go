@@ -0,0 +1,129 @@
+package blog
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"hawx.me/code/assert"
+	"hawx.me/code/tally-ho/internal/page"
+)
+
+func TestBuild(t *testing.T) {
+	assert := assert.New(t)
+
+	db, err := sql.Open("sqlite3", "file:build?mode=memory&cache=shared")
+	assert.Nil(err)
+
+	baseURL, _ := url.Parse("http://localhost:8080/")
+	b, err := New(slog.Default(), Config{
+		Me:       "http://localhost:8080/",
+		BaseURL:  baseURL,
+		PageSize: 1,
+	}, page.Context{Name: "test"}.WithPath("/"), db, fakeHubPublisher{}, nil)
+	assert.Nil(err)
+	defer b.Close()
+
+	var locations, cursors []string
+	for i := range 2 {
+		location, err := b.Create(map[string][]any{
+			"h":         {"entry"},
+			"content":   {fmt.Sprintf("note %d", i)},
+			"published": {fmt.Sprintf("2020-10-01T12:%02d:00Z", i)},
+			"category":  {"test", "two words"},
+		})
+		assert.Nil(err)
+		locations = append(locations, location)
+
+		data, err := b.Entry(location)
+		assert.Nil(err)
+		cursors = append(cursors, fmt.Sprintf("2020-10-01T12:%02d:00Z_%s", i, data["uid"][0]))
+	}
+
+	_, err = b.Create(map[string][]any{
+		"h":           {"entry"},
+		"content":     {"a draft"},
+		"category":    {"secret"},
+		"post-status": {"draft"},
+	})
+	assert.Nil(err)
+
+	deleted, err := b.Create(map[string][]any{
+		"h":         {"entry"},
+		"content":   {"a deleted note"},
+		"published": {"2020-09-01T12:00:00Z"},
+	})
+	assert.Nil(err)
+	assert.Nil(b.Delete(deleted))
+
+	assert.Nil(b.Update(locations[1], map[string][]any{"content": {"note 1, again"}}, nil, nil, nil))
+
+	dir := t.TempDir()
+	assert.Nil(b.Build(dir))
+
+	exists := func(path string) bool {
+		_, err := os.Stat(filepath.Join(dir, filepath.FromSlash(path)))
+		return err == nil
+	}
+
+	assert.True(exists("index.html"))
+	assert.True(exists("kind/note/index.html"))
+	assert.True(exists("kind/article/index.html"))
+	assert.True(exists("category/test/index.html"))
+	assert.True(exists("category/two words/index.html"))
+	assert.True(exists("category/two words/feed/atom"))
+	assert.True(exists("feed/atom"))
+	assert.True(exists("feed/jsonfeed"))
+	assert.True(exists("feed/rss"))
+	assert.True(exists("feed/2020/10/atom"))
+	assert.True(exists("feed/2020/10/rss"))
+	assert.False(exists("feed/2020/09/atom"))
+	assert.True(exists("kind/note/feed/atom"))
+	assert.True(exists("category/test/feed/jsonfeed"))
+	assert.True(exists("category/test/feed/2020/10/jsonfeed"))
+	assert.True(exists("2020/index.html"))
+	assert.True(exists("2020/10/index.html"))
+	assert.True(exists("2020/10/01/index.html"))
+	assert.True(exists("before/" + cursors[1] + "/index.html"))
+	assert.True(exists("after/" + cursors[0] + "/index.html"))
+	assert.True(exists("category/test/before/" + cursors[1] + "/index.html"))
+	assert.True(exists("category/test/after/" + cursors[0] + "/index.html"))
+
+	assert.False(exists("category/secret/index.html"))
+	assert.False(exists("category/secret/feed/atom"))
+
+	gone, err := os.ReadFile(filepath.Join(dir, strings.TrimPrefix(deleted, "http://localhost:8080/"), "index.html"))
+	assert.Nil(err)
+	assert.True(strings.Contains(string(gone), "This post has been deleted"))
+
+	updated, err := b.Entry(locations[1])
+	assert.Nil(err)
+	assert.True(exists("entry/" + updated["uid"][0].(string) + "/history/index.html"))
+
+	first, err := b.Entry(locations[0])
+	assert.Nil(err)
+	assert.False(exists("entry/" + first["uid"][0].(string) + "/history/index.html"))
+
+	entry := strings.TrimPrefix(locations[0], "http://localhost:8080/")
+	assert.True(exists(entry + "/index.html"))
+
+	data, err := b.Entry(locations[1])
+	assert.Nil(err)
+	redirect, err := os.ReadFile(filepath.Join(dir, "entry", data["uid"][0].(string), "index.html"))
+	assert.Nil(err)
+	assert.True(strings.Contains(string(redirect), `url=`+locations[1]))
+
+	assert.Nil(b.Delete(locations[0]))
+	assert.Nil(b.writePages(dir, []string{"/" + entry}))
+	tombstone, err := os.ReadFile(filepath.Join(dir, entry, "index.html"))
+	assert.Nil(err)
+	assert.True(strings.Contains(string(tombstone), "This post has been deleted"))
+
+	assert.Nil(b.writePages(dir, []string{"/not-a-page"}))
+	assert.False(exists("not-a-page/index.html"))
+}
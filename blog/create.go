@@ -4,6 +4,8 @@ import (
 	"errors"
 	"log/slog"
 	"slices"
+	"strconv"
+	"time"
 
 	"hawx.me/code/numbersix"
 	"hawx.me/code/tally-ho/internal/mfutil"
@@ -12,8 +14,15 @@ import (
 func (b *Blog) Create(data map[string][]any) (string, error) {
 	b.massage(data)
 
+	b.createMu.Lock()
+	defer b.createMu.Unlock()
+
 	uid := mfutil.Get(data, "uid").(string)
-	location := mfutil.Get(data, "url").(string)
+	location, err := b.uniqueURL(mfutil.Get(data, "url").(string))
+	if err != nil {
+		return location, err
+	}
+	data["url"] = []any{location}
 
 	triples, err := b.entries.List(numbersix.Where("uid", uid))
 	if err != nil {
@@ -24,17 +33,63 @@ func (b *Blog) Create(data map[string][]any) (string, error) {
 		return location, errors.New("post with uid already exists")
 	}
 
+	markScheduled(data, time.Now())
+
 	if err := b.entries.SetProperties(uid, data); err != nil {
 		return location, err
 	}
+	if err := b.revisions.Save(uid, data); err != nil {
+		return location, err
+	}
+	if err := b.search.Index(data); err != nil {
+		return location, err
+	}
 
 	slog.Info("set entry properties", slog.String("uid", uid), slog.String("url", location))
 
+	if isScheduled(data) {
+		slog.Info("scheduled entry", slog.String("uid", uid), slog.Any("published", data["published"][0]))
+	}
+
+	b.announce(location, data)
+	b.rebuild(data)
+
+	return location, nil
+}
+
+// announce performs the tasks for an entry that has just become visible. It
+// will do nothing for an entry that is not yet published, or is private.
+func (b *Blog) announce(location string, data map[string][]any) {
+	if !isPublished(data) || isPrivate(data) {
+		return
+	}
+
 	go b.syndicate(location, data)
 	go b.sendWebmentions(location, data)
-	go b.hubPublish()
 
-	return location, nil
+	if !isUnlisted(data) {
+		go b.hubPublish(data)
+	}
+}
+
+// uniqueURL returns location if no entry exists there, otherwise the first
+// location with a numbered suffix that is free.
+func (b *Blog) uniqueURL(location string) (string, error) {
+	candidate := location
+
+	for i := 2; ; i++ {
+		triples, err := b.entries.List(numbersix.Where("url", candidate))
+		if err != nil {
+			return "", err
+		}
+
+		// urls of purged entries are not reused, as they are known to be gone
+		if _, err := b.tombstones.ForURL(candidate); len(triples) == 0 && errors.Is(err, ErrNotFound) {
+			return candidate, nil
+		}
+
+		candidate = location + "-" + strconv.Itoa(i)
+	}
 }
 
 func contains(needle string, list []string) bool {
@@ -1,14 +1,53 @@
 package blog
 
 import (
+	"database/sql"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"hawx.me/code/assert"
+	"hawx.me/code/numbersix"
 )
 
+func TestUniqueURL(t *testing.T) {
+	assert := assert.New(t)
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	assert.Nil(err)
+
+	entries, err := numbersix.For(db, "entries")
+	assert.Nil(err)
+
+	tombstones, err := newTombstoneStore(db, "")
+	assert.Nil(err)
+
+	b := &Blog{entries: entries, tombstones: tombstones}
+
+	location, err := b.uniqueURL("http://example.com/2020/10/01/a-post")
+	assert.Nil(err)
+	assert.Equal("http://example.com/2020/10/01/a-post", location)
+
+	assert.Nil(entries.Set("1", "url", "http://example.com/2020/10/01/a-post"))
+
+	location, err = b.uniqueURL("http://example.com/2020/10/01/a-post")
+	assert.Nil(err)
+	assert.Equal("http://example.com/2020/10/01/a-post-2", location)
+
+	assert.Nil(entries.Set("2", "url", "http://example.com/2020/10/01/a-post-2"))
+
+	location, err = b.uniqueURL("http://example.com/2020/10/01/a-post")
+	assert.Nil(err)
+	assert.Equal("http://example.com/2020/10/01/a-post-3", location)
+
+	assert.Nil(tombstones.Save(Tombstone{UID: "3", URL: "http://example.com/2020/10/01/a-post-3"}))
+
+	location, err = b.uniqueURL("http://example.com/2020/10/01/a-post")
+	assert.Nil(err)
+	assert.Equal("http://example.com/2020/10/01/a-post-4", location)
+}
+
 func TestGetCite(t *testing.T) {
 	assert := assert.New(t)
 
@@ -3,16 +3,16 @@ package blog
 import (
 	"errors"
 	"fmt"
+	"slices"
+	"sort"
+	"strings"
 	"time"
 
 	"hawx.me/code/numbersix"
+	"hawx.me/code/tally-ho/internal/mfutil"
 )
 
-var (
-	ErrNotFound = errors.New("not found")
-
-	empty = map[string][]any{}
-)
+var ErrNotFound = errors.New("not found")
 
 func (b *Blog) Entry(url string) (data map[string][]any, err error) {
 	triples, err := b.entries.List(numbersix.Where("url", url))
@@ -51,10 +51,18 @@ func (b *Blog) Delete(url string) error {
 		return errors.New("post to delete not found")
 	}
 
-	go b.sendWebmentions(url, data)
-	go b.hubPublish()
+	b.announceChange(url, data)
+
+	if err := b.search.Remove(id); err != nil {
+		return err
+	}
 
-	return b.entries.Set(id, "hx-deleted", true)
+	if err := b.entries.Set(id, "hx-deleted", time.Now().UTC().Format(time.RFC3339)); err != nil {
+		return err
+	}
+
+	b.rebuild(data)
+	return nil
 }
 
 func (b *Blog) Undelete(url string) error {
@@ -68,10 +76,33 @@ func (b *Blog) Undelete(url string) error {
 		return errors.New("post to undelete not found")
 	}
 
+	b.announceChange(url, data)
+
+	if err := b.entries.DeletePredicate(id, "hx-deleted"); err != nil {
+		return err
+	}
+
+	delete(data, "hx-deleted")
+	if err := b.search.Index(data); err != nil {
+		return err
+	}
+
+	b.rebuild(data)
+	return nil
+}
+
+// announceChange lets the world know that a published entry has been removed or
+// restored.
+func (b *Blog) announceChange(url string, data map[string][]any) {
+	if !isPublished(data) || isPrivate(data) {
+		return
+	}
+
 	go b.sendWebmentions(url, data)
-	go b.hubPublish()
 
-	return b.entries.DeletePredicate(id, "hx-deleted")
+	if !isUnlisted(data) {
+		go b.hubPublish(data)
+	}
 }
 
 func (b *Blog) Mention(source string, data map[string][]any) error {
@@ -97,75 +128,202 @@ func (b *Blog) MentionsForEntry(url string) (list []numbersix.Group, err error)
 	return
 }
 
-func (b *Blog) MentionsBefore(published time.Time, limit int) (list []numbersix.Group, err error) {
-	triples, err := b.mentions.List(numbersix.
-		Before("published", published.Format(time.RFC3339)).
-		Limit(limit))
+// MentionsBefore returns at most limit mentions received before the given time,
+// newest first.
+func (b *Blog) MentionsBefore(published time.Time, limit int) ([]numbersix.Group, error) {
+	return itemsBefore(b.mentions, numbersix.All, timeCursor(published), limit)
+}
+
+// Before returns the entries published before the given time, newest first.
+func (b *Blog) Before(published time.Time) ([]numbersix.Group, error) {
+	groups, err := itemsBefore(b.entries, listed, timeCursor(published), b.pageSize())
 	if err != nil {
-		return
+		return nil, err
 	}
 
-	list = numbersix.Grouped(triples)
-	return
+	return b.groupedWithAuthors(groups), nil
 }
 
-func (b *Blog) Before(published time.Time) (groups []numbersix.Group, err error) {
-	triples, err := b.entries.List(
-		numbersix.
-			Before("published", published.Format(time.RFC3339)).
-			Without("hx-deleted").
-			Limit(25),
-	)
+// KindBefore returns the entries of a kind published before the given time,
+// newest first.
+func (b *Blog) KindBefore(kind string, published time.Time) ([]numbersix.Group, error) {
+	groups, err := itemsBefore(b.entries, listedKind(kind), timeCursor(published), b.pageSize())
 	if err != nil {
-		return
+		return nil, err
+	}
+
+	return b.groupedWithAuthors(groups), nil
+}
+
+// CategoryBefore returns the entries in a category published before the given
+// time, newest first.
+func (b *Blog) CategoryBefore(category string, published time.Time) ([]numbersix.Group, error) {
+	groups, err := itemsBefore(b.entries, listedCategory(category), timeCursor(published), b.pageSize())
+	if err != nil {
+		return nil, err
 	}
 
-	return b.groupedWithAuthors(numbersix.Grouped(triples)), nil
+	return b.groupedWithAuthors(groups), nil
 }
 
-func (b *Blog) KindBefore(kind string, published time.Time) (groups []numbersix.Group, err error) {
+// OwnedPage returns a page of at most n entries, newest first, of kind when it
+// is given. These are listed for the owner, so include those that are not
+// public. The page starts before, or after, the cursor given; otherwise it is
+// the latest entries. Cursors for the pages either side are returned, and are
+// empty when there is nothing more in that direction.
+func (b *Blog) OwnedPage(kind, before, after string, n int) (groups []numbersix.Group, newer, older string, err error) {
+	for _, c := range []string{before, after} {
+		if _, ok := parseCursor(c); c != "" && !ok {
+			return nil, "", "", ErrInvalidCursor
+		}
+	}
+
+	query := owned
+	if kind != "" {
+		query = ownedKind(kind)
+	}
+
+	p, err := readPage(b.entries, query, before, after, n)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	return b.groupedWithAuthors(p.Items), p.Newer, p.Older, nil
+}
+
+// LikesOn returns the likes published on the day, given as 2006-01-02, oldest
+// first.
+func (b *Blog) LikesOn(ymd string) (groups []numbersix.Group, err error) {
 	triples, err := b.entries.List(
-		numbersix.
-			Before("published", published.Format(time.RFC3339)).
-			Where("hx-kind", kind).
-			Without("hx-deleted").
-			Limit(25),
+		listed().
+			Begins("published", ymd).
+			Has("like-of"),
 	)
 	if err != nil {
 		return
 	}
 
-	return b.groupedWithAuthors(numbersix.Grouped(triples)), nil
+	return b.groupedWithAuthors(sortByPublished(numbersix.Grouped(triples))), nil
 }
 
-func (b *Blog) CategoryBefore(category string, published time.Time) (groups []numbersix.Group, err error) {
+// EntriesOn returns the entries published in the year, month or day, given as
+// 2006, 2006-01 or 2006-01-02, oldest first.
+func (b *Blog) EntriesOn(period string) (groups []numbersix.Group, err error) {
 	triples, err := b.entries.List(
-		numbersix.
-			Before("published", published.Format(time.RFC3339)).
-			Where("category", category).
-			Without("hx-deleted").
-			Limit(25),
+		listed().
+			Begins("published", period),
 	)
 	if err != nil {
 		return
 	}
 
-	return b.groupedWithAuthors(numbersix.Grouped(triples)), nil
+	return b.groupedWithAuthors(sortByPublished(numbersix.Grouped(triples))), nil
 }
 
-func (b *Blog) LikesOn(ymd string) (groups []numbersix.Group, err error) {
-	// TODO: this should be sorted
+// PublishedCounts returns the number of entries published in each year, month
+// and day that has any, keyed by 2006, 2006-01 and 2006-01-02 respectively.
+func (b *Blog) PublishedCounts() (map[string]int, error) {
 	triples, err := b.entries.List(
-		numbersix.
-			Begins("published", ymd).
-			Without("hx-deleted").
-			Has("like-of"),
+		listed().
+			Has("published"),
 	)
 	if err != nil {
-		return
+		return nil, err
+	}
+
+	counts := map[string]int{}
+	for _, group := range numbersix.Grouped(triples) {
+		published, _ := mfutil.Get(group.Properties, "published").(string)
+		if len(published) < len(time.DateOnly) {
+			continue
+		}
+
+		counts[published[:4]]++
+		counts[published[:7]]++
+		counts[published[:10]]++
+	}
+
+	return counts, nil
+}
+
+// sortByPublished orders the entries so that the oldest is first.
+func sortByPublished(groups []numbersix.Group) []numbersix.Group {
+	slices.SortStableFunc(groups, func(a, b numbersix.Group) int {
+		x, _ := mfutil.Get(a.Properties, "published").(string)
+		y, _ := mfutil.Get(b.Properties, "published").(string)
+
+		return strings.Compare(x, y)
+	})
+
+	return groups
+}
+
+// listed starts a query for entries that can be shown in lists and feeds.
+func listed() *numbersix.Query {
+	return numbersix.
+		Without("hx-deleted").
+		Without("hx-scheduled").
+		Without("hx-draft").
+		Without("hx-unlisted")
+}
+
+// owned starts a query for every entry the owner has, including drafts and
+// those that are scheduled, unlisted or private.
+func owned() *numbersix.Query {
+	return numbersix.Without("hx-deleted")
+}
+
+// listedKind returns a query for the listed entries of kind.
+func listedKind(kind string) func() *numbersix.Query {
+	return func() *numbersix.Query { return listed().Where("hx-kind", kind) }
+}
+
+// ownedKind returns a query for every entry of kind the owner has.
+func ownedKind(kind string) func() *numbersix.Query {
+	return func() *numbersix.Query { return owned().Where("hx-kind", kind) }
+}
+
+// listedCategory returns a query for the listed entries in category.
+func listedCategory(category string) func() *numbersix.Query {
+	return func() *numbersix.Query { return listed().Where("category", category) }
+}
+
+// Categories returns every category used by an entry, with the most used first.
+func (b *Blog) Categories() ([]string, error) {
+	return b.categories(owned().Has("category"))
+}
+
+// categories returns every category used by the entries matching query, with
+// the most used first.
+func (b *Blog) categories(query *numbersix.Query) ([]string, error) {
+	triples, err := b.entries.List(query)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := map[string]int{}
+	for _, group := range numbersix.Grouped(triples) {
+		for _, category := range group.Properties["category"] {
+			if s, ok := category.(string); ok {
+				counts[s]++
+			}
+		}
+	}
+
+	categories := make([]string, 0, len(counts))
+	for category := range counts {
+		categories = append(categories, category)
 	}
 
-	return b.groupedWithAuthors(numbersix.Grouped(triples)), nil
+	sort.Slice(categories, func(i, j int) bool {
+		if counts[categories[i]] == counts[categories[j]] {
+			return categories[i] < categories[j]
+		}
+
+		return counts[categories[i]] > counts[categories[j]]
+	})
+
+	return categories, nil
 }
 
 func (b *Blog) withAuthor(m map[string][]any) map[string][]any {
@@ -0,0 +1,169 @@
+package blog
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"hawx.me/code/assert"
+	"hawx.me/code/numbersix"
+)
+
+func TestEntriesOn(t *testing.T) {
+	assert := assert.New(t)
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	assert.Nil(err)
+
+	entries, err := numbersix.For(db, "entries")
+	assert.Nil(err)
+
+	b := &Blog{entries: entries}
+
+	for uid, published := range map[string]string{
+		"1": "2020-10-02T12:00:00Z",
+		"2": "2020-10-01T12:00:00Z",
+		"3": "2020-11-01T12:00:00Z",
+		"4": "2021-01-01T12:00:00Z",
+	} {
+		assert.Nil(entries.SetProperties(uid, map[string][]any{
+			"uid":       {uid},
+			"published": {published},
+		}))
+	}
+	assert.Nil(entries.SetProperties("5", map[string][]any{
+		"uid":        {"5"},
+		"published":  {"2020-10-01T13:00:00Z"},
+		"hx-deleted": {true},
+	}))
+
+	subjects := func(period string) []string {
+		groups, err := b.EntriesOn(period)
+		assert.Nil(err)
+
+		var subjects []string
+		for _, group := range groups {
+			subjects = append(subjects, group.Subject)
+		}
+		return subjects
+	}
+
+	assert.Equal([]string{"2", "1", "3"}, subjects("2020"))
+	assert.Equal([]string{"2", "1"}, subjects("2020-10"))
+	assert.Equal([]string{"2"}, subjects("2020-10-01"))
+	assert.Len(subjects("2019"), 0)
+
+	counts, err := b.PublishedCounts()
+	assert.Nil(err)
+	assert.Equal(map[string]int{
+		"2020":       3,
+		"2020-10":    2,
+		"2020-10-01": 1,
+		"2020-10-02": 1,
+		"2020-11":    1,
+		"2020-11-01": 1,
+		"2021":       1,
+		"2021-01":    1,
+		"2021-01-01": 1,
+	}, counts)
+}
+
+func TestOwnedPage(t *testing.T) {
+	assert := assert.New(t)
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	assert.Nil(err)
+
+	entries, err := numbersix.For(db, "entries")
+	assert.Nil(err)
+
+	b := &Blog{entries: entries}
+
+	for uid, hidden := range map[string][]string{
+		"1": nil,
+		"2": {"hx-draft"},
+		"3": {"hx-private", "hx-unlisted"},
+		"4": {"hx-unlisted"},
+		"5": {"hx-deleted"},
+	} {
+		data := map[string][]any{
+			"uid":       {uid},
+			"hx-kind":   {"note"},
+			"category":  {"cool"},
+			"published": {"2020-10-0" + uid + "T12:00:00Z"},
+		}
+		for _, key := range hidden {
+			data[key] = []any{true}
+		}
+		assert.Nil(entries.SetProperties(uid, data))
+	}
+
+	subjects := func(groups []numbersix.Group) []string {
+		var subjects []string
+		for _, group := range groups {
+			subjects = append(subjects, group.Subject)
+		}
+		return subjects
+	}
+
+	until, _ := time.Parse(time.RFC3339, "2020-11-01T12:00:00Z")
+
+	groups, err := b.Before(until)
+	assert.Nil(err)
+	assert.Equal([]string{"1"}, subjects(groups))
+
+	groups, err = b.KindBefore("note", until)
+	assert.Nil(err)
+	assert.Equal([]string{"1"}, subjects(groups))
+
+	groups, err = b.CategoryBefore("cool", until)
+	assert.Nil(err)
+	assert.Equal([]string{"1"}, subjects(groups))
+
+	groups, newer, older, err := b.OwnedPage("", "", "", 10)
+	assert.Nil(err)
+	assert.Equal([]string{"4", "3", "2", "1"}, subjects(groups))
+	assert.Equal("", newer)
+	assert.Equal("", older)
+
+	groups, newer, older, err = b.OwnedPage("note", "", "", 2)
+	assert.Nil(err)
+	assert.Equal([]string{"4", "3"}, subjects(groups))
+	assert.Equal("", newer)
+	assert.Equal("2020-10-03T12:00:00Z_3", older)
+
+	groups, newer, older, err = b.OwnedPage("note", older, "", 2)
+	assert.Nil(err)
+	assert.Equal([]string{"2", "1"}, subjects(groups))
+	assert.Equal("2020-10-02T12:00:00Z_2", newer)
+	assert.Equal("", older)
+
+	groups, _, _, err = b.OwnedPage("note", "", newer, 2)
+	assert.Nil(err)
+	assert.Equal([]string{"4", "3"}, subjects(groups))
+
+	groups, _, _, err = b.OwnedPage("article", "", "", 2)
+	assert.Nil(err)
+	assert.Empty(groups)
+
+	_, _, _, err = b.OwnedPage("", "yesterday", "", 2)
+	assert.Equal(ErrInvalidCursor, err)
+}
+
+func TestArchivePeriod(t *testing.T) {
+	assert := assert.New(t)
+
+	period, ok := archivePeriod(map[string]string{"year": "2020"})
+	assert.True(ok)
+	assert.Equal("2020", period)
+
+	period, ok = archivePeriod(map[string]string{"year": "2020", "month": "10", "day": "01"})
+	assert.True(ok)
+	assert.Equal("2020-10-01", period)
+
+	_, ok = archivePeriod(map[string]string{"year": "search"})
+	assert.False(ok)
+
+	_, ok = archivePeriod(map[string]string{"year": "2020", "month": "13"})
+	assert.False(ok)
+}
@@ -0,0 +1,558 @@
+package blog
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/feeds"
+	"hawx.me/code/numbersix"
+	"hawx.me/code/tally-ho/internal/mfutil"
+	"hawx.me/code/tally-ho/internal/page"
+)
+
+// feedFormats are the formats each feed is available in, named by the last
+// segment of its path.
+var feedFormats = []string{"atom", "jsonfeed", "rss"}
+
+// A feedPage is a document of a feed. The current document has the latest
+// entries, and older entries are archived by the month they were published in
+// as described by RFC 5005.
+type feedPage struct {
+	Title   string
+	HomeURL string
+	Author  string
+	Updated time.Time
+	Archive bool
+	Items   []feedItem
+
+	// PrevArchive and NextArchive are the months, as 2006-01, of the archives
+	// either side, and are empty when there is no archive in that direction.
+	PrevArchive string
+	NextArchive string
+}
+
+type feedItem struct {
+	URL        string
+	Title      string
+	Summary    string
+	Content    string
+	Published  time.Time
+	Updated    time.Time
+	AuthorName string
+	AuthorURL  string
+	Categories []string
+	Enclosures []feedEnclosure
+}
+
+type feedEnclosure struct {
+	URL    string
+	Type   string
+	Length int64
+}
+
+// feedLinks are the urls that a page of a feed links to, for the format it is
+// being written in. Those that are empty are left out.
+type feedLinks struct {
+	Self        string
+	Hub         string
+	Current     string
+	PrevArchive string
+	NextArchive string
+}
+
+// feedMonth is the layout of the months that feeds are archived by.
+const feedMonth = "2006-01"
+
+// feed returns the document of the feed for the list at path, of entries
+// matching query. When month is given this is the archive of the entries
+// published that month, which is only available once the month is over so
+// that it does not change. Otherwise it is the current document, which has the
+// latest entries along with any others published since the latest archive, so
+// that none are missed by following the archives back from it.
+func (b *Blog) feed(path, title string, query func() *numbersix.Query, month string) (feedPage, error) {
+	var (
+		now     = time.Now().UTC()
+		current = now.Format(feedMonth)
+		f       = feedPage{
+			Title:   title,
+			HomeURL: b.absoluteURL(path),
+			Author:  b.pageCtx.Name,
+			Archive: month != "",
+		}
+		posts []numbersix.Group
+		err   error
+	)
+
+	if month == "" {
+		month = current
+
+		posts, err = itemsBefore(b.entries, query, timeCursor(now), b.pageSize())
+		if err != nil {
+			return f, err
+		}
+
+		if len(posts) == b.pageSize() && monthOf(posts[len(posts)-1]) == current {
+			if posts, err = b.monthEntries(query, current); err != nil {
+				return f, err
+			}
+			posts = slices.DeleteFunc(posts, func(post numbersix.Group) bool {
+				return publishedOf(post) > now.Format(time.RFC3339)
+			})
+		}
+	} else {
+		if _, err := time.Parse(feedMonth, month); err != nil || month >= current {
+			return f, fmt.Errorf("feed archive %s: %w", month, ErrNotFound)
+		}
+
+		if posts, err = b.monthEntries(query, month); err != nil {
+			return f, err
+		}
+		if len(posts) == 0 {
+			return f, fmt.Errorf("feed archive %s: %w", month, ErrNotFound)
+		}
+
+		if f.NextArchive, err = b.nextArchive(query, month); err != nil {
+			return f, err
+		}
+		if f.NextArchive >= current {
+			f.NextArchive = ""
+		}
+	}
+
+	if f.PrevArchive, err = b.prevArchive(query, month); err != nil {
+		return f, err
+	}
+
+	for _, post := range b.groupedWithAuthors(posts) {
+		item := b.feedItem(post.Properties)
+		if item.Updated.After(f.Updated) {
+			f.Updated = item.Updated
+		}
+
+		f.Items = append(f.Items, item)
+	}
+
+	if f.Updated.IsZero() {
+		f.Updated = now
+	}
+
+	return f, nil
+}
+
+// monthEntries returns the entries matching query published in the month,
+// newest first.
+func (b *Blog) monthEntries(query func() *numbersix.Query, month string) ([]numbersix.Group, error) {
+	triples, err := b.entries.List(query().Begins("published", month))
+	if err != nil {
+		return nil, err
+	}
+
+	groups := numbersix.Grouped(triples)
+	slices.SortFunc(groups, func(a, b numbersix.Group) int {
+		return compareItems(b, a)
+	})
+
+	return groups, nil
+}
+
+// prevArchive returns the latest month before the month that has entries
+// matching query, or nothing if there is none.
+func (b *Blog) prevArchive(query func() *numbersix.Query, month string) (string, error) {
+	start, _ := time.Parse(feedMonth, month)
+
+	older, err := itemsBefore(b.entries, query, timeCursor(start), 1)
+	if err != nil || len(older) == 0 {
+		return "", err
+	}
+
+	return monthOf(older[0]), nil
+}
+
+// nextArchive returns the earliest month after the month that has entries
+// matching query, or nothing if there is none.
+func (b *Blog) nextArchive(query func() *numbersix.Query, month string) (string, error) {
+	start, _ := time.Parse(feedMonth, month)
+	end := start.AddDate(0, 1, 0).Add(-time.Second)
+
+	newer, err := itemsAfter(b.entries, query, timeCursor(end), 1)
+	if err != nil || len(newer) == 0 {
+		return "", err
+	}
+
+	return monthOf(newer[0]), nil
+}
+
+func monthOf(group numbersix.Group) string {
+	published, _ := time.Parse(time.RFC3339, publishedOf(group))
+	return published.Format(feedMonth)
+}
+
+func (b *Blog) feedItem(data map[string][]any) feedItem {
+	relURL, _ := url.Parse(data["url"][0].(string))
+
+	item := feedItem{
+		URL:        b.config.BaseURL.ResolveReference(relURL).String(),
+		Title:      page.DecideTitle(data),
+		Content:    feedContent(data),
+		Categories: searchStrings(data["category"]),
+	}
+
+	item.Summary, _ = mfutil.Get(data, "summary").(string)
+	item.AuthorName, _ = mfutil.Get(data, "author.properties.name").(string)
+	item.AuthorURL, _ = mfutil.Get(data, "author.properties.url", "author").(string)
+
+	published, _ := mfutil.Get(data, "published").(string)
+	item.Published, _ = time.Parse(time.RFC3339, published)
+
+	item.Updated = item.Published
+	if updated, ok := mfutil.Get(data, "updated").(string); ok {
+		if t, err := time.Parse(time.RFC3339, updated); err == nil {
+			item.Updated = t
+		}
+	}
+
+	fw := &FileWriter{MediaDir: b.config.MediaDir, MediaURL: b.config.MediaURL}
+	for _, key := range []string{"photo", "video", "audio"} {
+		for _, value := range data[key] {
+			location := mediaLocation(value)
+			if location == "" {
+				continue
+			}
+
+			enclosure := feedEnclosure{URL: location, Type: "application/octet-stream"}
+			if u, err := url.Parse(location); err == nil {
+				if t := mime.TypeByExtension(path.Ext(u.Path)); t != "" {
+					enclosure.Type = t
+				}
+			}
+			enclosure.Length, _ = fw.Size(location)
+
+			item.Enclosures = append(item.Enclosures, enclosure)
+		}
+	}
+
+	return item
+}
+
+// feedContent renders the content of an entry, followed by its media, so that
+// the whole entry can be read in feed readers.
+func feedContent(data map[string][]any) string {
+	var sb strings.Builder
+
+	if s, ok := mfutil.Get(data, "content.html").(string); ok {
+		sb.WriteString(s)
+	} else if s, ok := mfutil.Get(data, "content.text", "content").(string); ok {
+		sb.WriteString(html.EscapeString(s))
+	}
+
+	for _, photo := range data["photo"] {
+		alt, _ := mfutil.Get(photo, "alt").(string)
+		fmt.Fprintf(&sb, `<img src="%s" alt="%s">`, html.EscapeString(mediaLocation(photo)), html.EscapeString(alt))
+	}
+	for _, video := range data["video"] {
+		fmt.Fprintf(&sb, `<video src="%s" controls></video>`, html.EscapeString(mediaLocation(video)))
+	}
+	for _, audio := range data["audio"] {
+		fmt.Fprintf(&sb, `<audio src="%s" controls></audio>`, html.EscapeString(mediaLocation(audio)))
+	}
+
+	return sb.String()
+}
+
+// mediaLocation returns the url of a photo, video or audio value, which may be
+// given as a url or as an object with a value and alt text.
+func mediaLocation(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case map[string]any:
+		location, _ := v["value"].(string)
+		return location
+	}
+
+	return ""
+}
+
+// writeFeed writes the page of the feed to w in format.
+func writeFeed(w http.ResponseWriter, format string, f feedPage, links feedLinks) error {
+	w.Header().Add("Link", `<`+links.Self+`>; rel="self"`)
+	if links.Hub != "" {
+		w.Header().Add("Link", `<`+links.Hub+`>; rel="hub"`)
+	}
+
+	switch format {
+	case "atom":
+		w.Header().Set("Content-Type", "application/atom+xml")
+		return feeds.WriteXML(f.atom(links), w)
+	case "rss":
+		w.Header().Set("Content-Type", "application/rss+xml")
+		return feeds.WriteXML(f.rss(links), w)
+	case "jsonfeed":
+		w.Header().Set("Content-Type", "application/json")
+		return json.NewEncoder(w).Encode(f.jsonFeed(links))
+	}
+
+	return fmt.Errorf("feed format %s: %w", format, ErrNotFound)
+}
+
+// feed returns the page as a feeds.Feed. This has the parts that each format
+// shares, the rest are added to the format after it is created.
+func (f feedPage) feed() *feeds.Feed {
+	feed := &feeds.Feed{
+		Title:   f.Title,
+		Link:    &feeds.Link{Href: f.HomeURL},
+		Author:  &feeds.Author{Name: f.Author},
+		Updated: f.Updated,
+	}
+
+	for _, item := range f.Items {
+		feedItem := &feeds.Item{
+			Id:          item.URL,
+			Title:       item.Title,
+			Link:        &feeds.Link{Href: item.URL, Type: "text/html"},
+			Description: item.Summary,
+			Content:     item.Content,
+			Created:     item.Published,
+			Updated:     item.Updated,
+		}
+		if item.AuthorName != "" {
+			feedItem.Author = &feeds.Author{Name: item.AuthorName}
+		}
+
+		feed.Add(feedItem)
+	}
+
+	return feed
+}
+
+// atomFeed adds the links for RFC 5005 archives to a feeds.AtomFeed, and the
+// element that marks the document as an archive.
+type atomFeed struct {
+	*feeds.AtomFeed
+	FHNamespace string           `xml:"xmlns:fh,attr"`
+	Archive     *struct{}        `xml:"fh:archive"`
+	Links       []feeds.AtomLink `xml:"link"`
+	Entries     []atomEntry      `xml:"entry"`
+}
+
+// atomEntry adds the categories of an entry, as feeds.AtomEntry can only have
+// one which is written as text instead of as a term.
+type atomEntry struct {
+	*feeds.AtomEntry
+	Categories []atomCategory `xml:"category"`
+}
+
+func (f *atomFeed) FeedXml() any { return f }
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+func (f feedPage) atom(links feedLinks) *atomFeed {
+	feed := (&feeds.Atom{Feed: f.feed()}).AtomFeed()
+	feed.Id = links.Current
+
+	doc := &atomFeed{
+		AtomFeed:    feed,
+		FHNamespace: "http://purl.org/syndication/history/1.0",
+		Links:       append(atomLinks(links), feeds.AtomLink{Rel: "alternate", Href: f.HomeURL, Type: "text/html"}),
+	}
+	if f.Archive {
+		doc.Archive = &struct{}{}
+	}
+
+	for i, entry := range feed.Entries {
+		item := f.Items[i]
+
+		entry.Published = item.Published.Format(time.RFC3339)
+		if item.Summary == "" {
+			entry.Summary = nil
+		}
+		if entry.Author != nil {
+			entry.Author.Uri = item.AuthorURL
+		}
+		for _, enclosure := range item.Enclosures {
+			entry.Links = append(entry.Links, feeds.AtomLink{
+				Rel:    "enclosure",
+				Href:   enclosure.URL,
+				Type:   enclosure.Type,
+				Length: lengthAttr(enclosure.Length),
+			})
+		}
+
+		e := atomEntry{AtomEntry: entry}
+		for _, category := range item.Categories {
+			e.Categories = append(e.Categories, atomCategory{Term: category})
+		}
+
+		doc.Entries = append(doc.Entries, e)
+	}
+	feed.Entries = nil
+
+	return doc
+}
+
+// rssFeed adds the namespaces used by rssChannel to a feeds.RssFeedXml.
+type rssFeed struct {
+	XMLName          xml.Name   `xml:"rss"`
+	Version          string     `xml:"version,attr"`
+	ContentNamespace string     `xml:"xmlns:content,attr"`
+	AtomNamespace    string     `xml:"xmlns:atom,attr"`
+	DCNamespace      string     `xml:"xmlns:dc,attr"`
+	FHNamespace      string     `xml:"xmlns:fh,attr"`
+	Channel          rssChannel `xml:"channel"`
+}
+
+func (f *rssFeed) FeedXml() any { return f }
+
+// rssChannel adds the links for RFC 5005 archives to a feeds.RssFeed, which
+// RSS has no elements of its own for so are given as Atom links.
+type rssChannel struct {
+	*feeds.RssFeed
+	Archive *struct{}  `xml:"fh:archive"`
+	Links   []atomLink `xml:"atom:link"`
+	Items   []rssItem  `xml:"item"`
+}
+
+// rssItem adds the author and categories of an item to a feeds.RssItem. RSS
+// expects an author to be given as an email address, so they are given by name
+// as a Dublin Core creator instead.
+type rssItem struct {
+	*feeds.RssItem
+	Creator    string   `xml:"dc:creator,omitempty"`
+	Categories []string `xml:"category"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr"`
+	Href string `xml:"href,attr"`
+}
+
+func (f feedPage) rss(links feedLinks) *rssFeed {
+	channel := (&feeds.Rss{Feed: f.feed()}).RssFeed()
+	channel.Description = f.Title
+	channel.ManagingEditor = ""
+
+	doc := &rssFeed{
+		Version:          "2.0",
+		ContentNamespace: "http://purl.org/rss/1.0/modules/content/",
+		AtomNamespace:    "http://www.w3.org/2005/Atom",
+		DCNamespace:      "http://purl.org/dc/elements/1.1/",
+		FHNamespace:      "http://purl.org/syndication/history/1.0",
+		Channel:          rssChannel{RssFeed: channel},
+	}
+	if f.Archive {
+		doc.Channel.Archive = &struct{}{}
+	}
+	for _, link := range atomLinks(links) {
+		doc.Channel.Links = append(doc.Channel.Links, atomLink{Rel: link.Rel, Href: link.Href})
+	}
+
+	for i, rss := range channel.Items {
+		item := f.Items[i]
+
+		rss.Author = ""
+		if rss.Description == "" {
+			rss.Description = item.Content
+		}
+
+		// readers only expect an item to have a single enclosure, the rest can
+		// still be seen in the content
+		if len(item.Enclosures) > 0 {
+			enclosure := item.Enclosures[0]
+			rss.Enclosure = &feeds.RssEnclosure{
+				Url:    enclosure.URL,
+				Type:   enclosure.Type,
+				Length: strconv.FormatInt(enclosure.Length, 10),
+			}
+		}
+
+		doc.Channel.Items = append(doc.Channel.Items, rssItem{
+			RssItem:    rss,
+			Creator:    item.AuthorName,
+			Categories: item.Categories,
+		})
+	}
+	channel.Items = nil
+
+	return doc
+}
+
+// jsonFeed adds hubs to a feeds.JSONFeed, as it gives them the type of an item.
+type jsonFeed struct {
+	*feeds.JSONFeed
+	Hubs []jsonFeedHub `json:"hubs,omitempty"`
+}
+
+type jsonFeedHub struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+// jsonFeed returns the page as a JSON Feed. This has no archives, but can be
+// paged through by following each next_url to older entries.
+func (f feedPage) jsonFeed(links feedLinks) *jsonFeed {
+	feed := (&feeds.JSON{Feed: f.feed()}).JSONFeed()
+	feed.FeedUrl = links.Self
+	feed.NextUrl = links.PrevArchive
+
+	doc := &jsonFeed{JSONFeed: feed}
+	if links.Hub != "" {
+		doc.Hubs = []jsonFeedHub{{Type: "WebSub", URL: links.Hub}}
+	}
+
+	for i, j := range feed.Items {
+		item := f.Items[i]
+
+		j.Tags = item.Categories
+		if j.Author != nil {
+			j.Author.Url = item.AuthorURL
+		}
+		for _, enclosure := range item.Enclosures {
+			j.Attachments = append(j.Attachments, feeds.JSONAttachment{
+				Url:      enclosure.URL,
+				MIMEType: enclosure.Type,
+				Size:     int32(enclosure.Length),
+			})
+		}
+	}
+
+	return doc
+}
+
+// atomLinks returns a link for each of the urls that are set. These are used by
+// both Atom and RSS, which has no equivalent of its own.
+func atomLinks(links feedLinks) []feeds.AtomLink {
+	var atomLinks []feeds.AtomLink
+	for _, l := range []feeds.AtomLink{
+		{Rel: "self", Href: links.Self},
+		{Rel: "hub", Href: links.Hub},
+		{Rel: "current", Href: links.Current},
+		{Rel: "prev-archive", Href: links.PrevArchive},
+		{Rel: "next-archive", Href: links.NextArchive},
+	} {
+		if l.Href != "" {
+			atomLinks = append(atomLinks, l)
+		}
+	}
+
+	return atomLinks
+}
+
+func lengthAttr(length int64) string {
+	if length <= 0 {
+		return ""
+	}
+
+	return strconv.FormatInt(length, 10)
+}
@@ -0,0 +1,432 @@
+package blog
+
+import (
+	"database/sql"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"log/slog"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"hawx.me/code/assert"
+	"hawx.me/code/tally-ho/internal/page"
+)
+
+func TestFeed(t *testing.T) {
+	assert := assert.New(t)
+
+	db, err := sql.Open("sqlite3", "file:feed?mode=memory&cache=shared")
+	assert.Nil(err)
+
+	mediaDir := t.TempDir()
+	assert.Nil(os.WriteFile(filepath.Join(mediaDir, "photo.jpg"), []byte("a photo"), 0o644))
+
+	baseURL, _ := url.Parse("http://localhost:8080/")
+	mediaURL, _ := url.Parse("http://localhost:8081/")
+	b, err := New(slog.Default(), Config{
+		Me:       "http://localhost:8080/",
+		BaseURL:  baseURL,
+		MediaURL: mediaURL,
+		MediaDir: mediaDir,
+		HubURL:   "http://localhost:8080/-/hub",
+		PageSize: 1,
+	}, page.Context{Name: "test", Author: "John Doe"}.WithPath("/"), db, fakeHubPublisher{}, nil)
+	assert.Nil(err)
+	defer b.Close()
+
+	older, err := b.Create(map[string][]any{
+		"h":         {"entry"},
+		"content":   {"an older note"},
+		"published": {"2020-09-15T12:00:00Z"},
+	})
+	assert.Nil(err)
+
+	location, err := b.Create(map[string][]any{
+		"h":         {"entry"},
+		"content":   {map[string]any{"html": "<p>a <em>photo</em></p>"}},
+		"photo":     {map[string]any{"value": "http://localhost:8081/photo.jpg", "alt": "a cat"}},
+		"video":     {"http://localhost:8081/video.mp4"},
+		"category":  {"cats", "photos"},
+		"published": {"2020-10-02T12:00:00Z"},
+		"updated":   {"2020-10-03T12:00:00Z"},
+	})
+	assert.Nil(err)
+
+	_, err = b.Create(map[string][]any{
+		"h":         {"entry"},
+		"content":   {"a later note"},
+		"published": {"2020-10-05T12:00:00Z"},
+	})
+	assert.Nil(err)
+
+	handler := b.Handler()
+	get := func(path string) string {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		assert.Equal(200, w.Code)
+		return w.Body.String()
+	}
+
+	current := parseAtom(t, get("/feed/atom"))
+	assert.Equal("http://localhost:8080/feed/atom", current.ID)
+	assert.Equal(map[string]string{
+		"self":         "http://localhost:8080/feed/atom",
+		"hub":          "http://localhost:8080/-/hub",
+		"current":      "http://localhost:8080/feed/atom",
+		"prev-archive": "http://localhost:8080/feed/2020/10/atom",
+		"alternate":    "http://localhost:8080/",
+	}, linkRels(current.Links))
+	assert.Nil(current.Archive)
+	if assert.Len(current.Entries, 1) {
+		assert.Equal("a later note", current.Entries[0].Content.Value)
+	}
+
+	october := parseAtom(t, get("/feed/2020/10/atom"))
+	assert.Equal(map[string]string{
+		"self":         "http://localhost:8080/feed/2020/10/atom",
+		"current":      "http://localhost:8080/feed/atom",
+		"prev-archive": "http://localhost:8080/feed/2020/09/atom",
+		"alternate":    "http://localhost:8080/",
+	}, linkRels(october.Links))
+	assert.NotNil(october.Archive)
+	if assert.Len(october.Entries, 2) {
+		assert.Equal("a later note", october.Entries[0].Content.Value)
+
+		entry := october.Entries[1]
+		assert.Equal(location, entry.ID)
+		assert.Equal("html", entry.Content.Type)
+		assert.Equal(`<p>a <em>photo</em></p><img src="http://localhost:8081/photo.jpg" alt="a cat"><video src="http://localhost:8081/video.mp4" controls></video>`, entry.Content.Value)
+		assert.Equal("2020-10-02T12:00:00Z", entry.Published)
+		assert.Equal("2020-10-03T12:00:00Z", entry.Updated)
+		assert.Equal("John Doe", entry.Author.Name)
+		assert.Equal([]testCategory{{Term: "cats"}, {Term: "photos"}}, entry.Categories)
+		if assert.Len(entry.Links, 3) {
+			assert.Equal(testLink{Rel: "enclosure", Href: "http://localhost:8081/photo.jpg", Type: "image/jpeg", Length: "7"}, entry.Links[1])
+			assert.Equal("http://localhost:8081/video.mp4", entry.Links[2].Href)
+		}
+	}
+
+	// the archives link to each other, so can be followed either way
+	september := parseAtom(t, get("/feed/2020/09/atom"))
+	assert.Equal(map[string]string{
+		"self":         "http://localhost:8080/feed/2020/09/atom",
+		"current":      "http://localhost:8080/feed/atom",
+		"next-archive": "http://localhost:8080/feed/2020/10/atom",
+		"alternate":    "http://localhost:8080/",
+	}, linkRels(september.Links))
+	if assert.Len(september.Entries, 1) {
+		assert.Equal(older, september.Entries[0].ID)
+	}
+
+	var rss struct {
+		Version string `xml:"version,attr"`
+		Channel struct {
+			Archive *struct{}  `xml:"http://purl.org/syndication/history/1.0 archive"`
+			Links   []testLink `xml:"http://www.w3.org/2005/Atom link"`
+			Items   []struct {
+				GUID       string   `xml:"guid"`
+				PubDate    string   `xml:"pubDate"`
+				Content    string   `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+				Creator    string   `xml:"http://purl.org/dc/elements/1.1/ creator"`
+				Categories []string `xml:"category"`
+				Enclosure  struct {
+					URL    string `xml:"url,attr"`
+					Length string `xml:"length,attr"`
+					Type   string `xml:"type,attr"`
+				} `xml:"enclosure"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}
+	assert.Nil(xml.Unmarshal([]byte(get("/feed/2020/10/rss")), &rss))
+	assert.Equal("2.0", rss.Version)
+	assert.NotNil(rss.Channel.Archive)
+	assert.Equal(map[string]string{
+		"self":         "http://localhost:8080/feed/2020/10/rss",
+		"current":      "http://localhost:8080/feed/rss",
+		"prev-archive": "http://localhost:8080/feed/2020/09/rss",
+	}, linkRels(rss.Channel.Links))
+	if assert.Len(rss.Channel.Items, 2) {
+		item := rss.Channel.Items[1]
+		assert.Equal(location, item.GUID)
+		assert.True(strings.HasPrefix(item.Content, "<p>a <em>photo</em></p>"))
+		assert.Equal("John Doe", item.Creator)
+		assert.Equal("Fri, 02 Oct 2020 12:00:00 +0000", item.PubDate)
+		assert.Equal([]string{"cats", "photos"}, item.Categories)
+		assert.Equal("http://localhost:8081/photo.jpg", item.Enclosure.URL)
+		assert.Equal("7", item.Enclosure.Length)
+		assert.Equal("image/jpeg", item.Enclosure.Type)
+	}
+
+	type jsonFeed struct {
+		NextURL string `json:"next_url"`
+		Hubs    []struct {
+			Type string `json:"type"`
+			URL  string `json:"url"`
+		} `json:"hubs"`
+		Items []struct {
+			ID           string   `json:"id"`
+			ContentHTML  string   `json:"content_html"`
+			DateModified string   `json:"date_modified"`
+			Tags         []string `json:"tags"`
+			Author       struct {
+				Name string `json:"name"`
+			} `json:"author"`
+			Attachments []struct {
+				URL      string `json:"url"`
+				MimeType string `json:"mime_type"`
+			} `json:"attachments"`
+		} `json:"items"`
+	}
+
+	var jsonCurrent jsonFeed
+	assert.Nil(json.Unmarshal([]byte(get("/feed/jsonfeed")), &jsonCurrent))
+	assert.Equal("http://localhost:8080/feed/2020/10/jsonfeed", jsonCurrent.NextURL)
+	if assert.Len(jsonCurrent.Hubs, 1) {
+		assert.Equal("WebSub", jsonCurrent.Hubs[0].Type)
+		assert.Equal("http://localhost:8080/-/hub", jsonCurrent.Hubs[0].URL)
+	}
+
+	var jsonOctober jsonFeed
+	assert.Nil(json.Unmarshal([]byte(get("/feed/2020/10/jsonfeed")), &jsonOctober))
+	assert.Equal("http://localhost:8080/feed/2020/09/jsonfeed", jsonOctober.NextURL)
+	if assert.Len(jsonOctober.Items, 2) {
+		item := jsonOctober.Items[1]
+		assert.Equal(location, item.ID)
+		assert.True(strings.HasPrefix(item.ContentHTML, "<p>a <em>photo</em></p>"))
+		assert.Equal("2020-10-03T12:00:00Z", item.DateModified)
+		assert.Equal([]string{"cats", "photos"}, item.Tags)
+		assert.Equal("John Doe", item.Author.Name)
+		if assert.Len(item.Attachments, 2) {
+			assert.Equal("image/jpeg", item.Attachments[0].MimeType)
+			assert.Equal("http://localhost:8081/video.mp4", item.Attachments[1].URL)
+		}
+	}
+
+	for _, path := range []string{
+		"/feed/2020/08/atom",
+		"/feed/2020/13/atom",
+		"/feed/" + time.Now().UTC().Format("2006/01") + "/atom",
+	} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		assert.Equal(404, w.Code, path)
+	}
+}
+
+func TestFeedCurrentMonth(t *testing.T) {
+	assert := assert.New(t)
+
+	db, err := sql.Open("sqlite3", "file:feedmonth?mode=memory&cache=shared")
+	assert.Nil(err)
+
+	baseURL, _ := url.Parse("http://localhost:8080/")
+	b, err := New(slog.Default(), Config{
+		Me:       "http://localhost:8080/",
+		BaseURL:  baseURL,
+		PageSize: 1,
+	}, page.Context{Name: "test"}.WithPath("/"), db, fakeHubPublisher{}, nil)
+	assert.Nil(err)
+	defer b.Close()
+
+	// the current document has everything published since the latest archive,
+	// even when that is more than a page
+	month, _ := time.Parse("2006-01", time.Now().UTC().Format("2006-01"))
+	for i := range 3 {
+		_, err = b.Create(map[string][]any{
+			"h":         {"entry"},
+			"content":   {fmt.Sprintf("note %d", i)},
+			"published": {month.Add(time.Duration(i) * time.Second).Format(time.RFC3339)},
+		})
+		assert.Nil(err)
+	}
+	_, err = b.Create(map[string][]any{
+		"h":         {"entry"},
+		"content":   {"an older note"},
+		"published": {"2020-10-01T12:00:00Z"},
+	})
+	assert.Nil(err)
+
+	w := httptest.NewRecorder()
+	b.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/feed/atom", nil))
+	assert.Equal(200, w.Code)
+
+	current := parseAtom(t, w.Body.String())
+	var contents []string
+	for _, entry := range current.Entries {
+		contents = append(contents, entry.Content.Value)
+	}
+	assert.Equal([]string{"note 2", "note 1", "note 0"}, contents)
+	assert.Equal("http://localhost:8080/feed/2020/10/atom", linkRels(current.Links)["prev-archive"])
+}
+
+type testAtomFeed struct {
+	ID      string     `xml:"id"`
+	Archive *struct{}  `xml:"http://purl.org/syndication/history/1.0 archive"`
+	Links   []testLink `xml:"link"`
+	Entries []struct {
+		ID        string `xml:"id"`
+		Published string `xml:"published"`
+		Updated   string `xml:"updated"`
+		Content   struct {
+			Type  string `xml:"type,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"content"`
+		Author struct {
+			Name string `xml:"name"`
+		} `xml:"author"`
+		Categories []testCategory `xml:"category"`
+		Links      []testLink     `xml:"link"`
+	} `xml:"entry"`
+}
+
+type testLink struct {
+	Rel    string `xml:"rel,attr"`
+	Href   string `xml:"href,attr"`
+	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr"`
+}
+
+type testCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+func parseAtom(t *testing.T, body string) testAtomFeed {
+	var feed testAtomFeed
+	if err := xml.Unmarshal([]byte(body), &feed); err != nil {
+		t.Fatal(err)
+	}
+
+	return feed
+}
+
+func linkRels(links []testLink) map[string]string {
+	rels := map[string]string{}
+	for _, link := range links {
+		rels[link.Rel] = link.Href
+	}
+
+	return rels
+}
+
+func TestKindAndCategoryFeeds(t *testing.T) {
+	assert := assert.New(t)
+
+	db, err := sql.Open("sqlite3", "file:kindfeed?mode=memory&cache=shared")
+	assert.Nil(err)
+
+	baseURL, _ := url.Parse("http://localhost:8080/")
+	b, err := New(slog.Default(), Config{
+		Me:      "http://localhost:8080/",
+		BaseURL: baseURL,
+		HubURL:  "http://localhost:8080/-/hub",
+	}, page.Context{Name: "test"}.WithPath("/"), db, fakeHubPublisher{}, nil)
+	assert.Nil(err)
+	defer b.Close()
+
+	_, err = b.Create(map[string][]any{
+		"h":         {"entry"},
+		"content":   {"a note about go"},
+		"category":  {"go"},
+		"published": {"2020-10-01T12:00:00Z"},
+	})
+	assert.Nil(err)
+
+	_, err = b.Create(map[string][]any{
+		"h":         {"entry"},
+		"content":   {"a note about cats and dogs"},
+		"category":  {"cats & dogs"},
+		"published": {"2020-09-01T12:00:00Z"},
+	})
+	assert.Nil(err)
+
+	_, err = b.Create(map[string][]any{
+		"h":         {"entry"},
+		"name":      {"An article"},
+		"content":   {"an article about cats"},
+		"category":  {"cats"},
+		"published": {"2020-10-02T12:00:00Z"},
+	})
+	assert.Nil(err)
+
+	handler := b.Handler()
+	get := func(path string) (int, string) {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w.Code, w.Body.String()
+	}
+
+	code, body := get("/kind/article/feed/atom")
+	assert.Equal(200, code)
+	assert.True(strings.Contains(body, "<title>test article posts</title>"))
+	assert.True(strings.Contains(body, `<link href="http://localhost:8080/kind/article/feed/atom" rel="self"></link>`))
+	assert.True(strings.Contains(body, `<link href="http://localhost:8080/kind/article/" rel="alternate" type="text/html"></link>`))
+	assert.True(strings.Contains(body, "an article about cats"))
+	assert.False(strings.Contains(body, "a note about go"))
+
+	code, body = get("/category/go/feed/rss")
+	assert.Equal(200, code)
+	assert.True(strings.Contains(body, "<title>test posts in go</title>"))
+	assert.True(strings.Contains(body, "a note about go"))
+	assert.False(strings.Contains(body, "an article about cats"))
+
+	code, body = get("/category/cats%20&%20dogs/feed/atom")
+	assert.Equal(200, code)
+	assert.True(strings.Contains(body, `<link href="http://localhost:8080/category/cats%20&amp;%20dogs/feed/atom" rel="self"></link>`))
+	assert.True(strings.Contains(body, "a note about cats and dogs"))
+
+	code, _ = get("/kind/what/feed/jsonfeed")
+	assert.Equal(404, code)
+
+	code, body = get("/category/go")
+	assert.Equal(200, code)
+	assert.True(strings.Contains(body, `<link href="/category/go/feed/atom" rel="alternate" title="test posts in go" type="application/atom+xml">`))
+	assert.True(strings.Contains(body, `<link href="/feed/atom" rel="alternate" title="test posts" type="application/atom+xml">`))
+}
+
+type recordingHubPublisher struct {
+	topics *[]string
+}
+
+func (p recordingHubPublisher) Publish(topic string) error {
+	*p.topics = append(*p.topics, topic)
+	return nil
+}
+
+func TestHubPublishFeeds(t *testing.T) {
+	assert := assert.New(t)
+
+	var topics []string
+	baseURL, _ := url.Parse("http://localhost:8080/")
+	b := &Blog{
+		config:       Config{BaseURL: baseURL},
+		pageCtx:      page.Context{Name: "test"}.WithPath("/"),
+		hubPublisher: recordingHubPublisher{topics: &topics},
+	}
+
+	b.hubPublish(
+		map[string][]any{"hx-kind": {"note"}, "category": {"go"}},
+		map[string][]any{"hx-kind": {"note"}, "category": {"go", "cats & dogs"}},
+	)
+
+	assert.Equal([]string{
+		"http://localhost:8080/",
+		"http://localhost:8080/feed/atom",
+		"http://localhost:8080/feed/jsonfeed",
+		"http://localhost:8080/feed/rss",
+		"http://localhost:8080/kind/note/feed/atom",
+		"http://localhost:8080/kind/note/feed/jsonfeed",
+		"http://localhost:8080/kind/note/feed/rss",
+		"http://localhost:8080/category/go/feed/atom",
+		"http://localhost:8080/category/go/feed/jsonfeed",
+		"http://localhost:8080/category/go/feed/rss",
+		"http://localhost:8080/category/cats%20&%20dogs/feed/atom",
+		"http://localhost:8080/category/cats%20&%20dogs/feed/jsonfeed",
+		"http://localhost:8080/category/cats%20&%20dogs/feed/rss",
+	}, topics)
+}
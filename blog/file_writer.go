@@ -44,6 +44,90 @@ func (fw *FileWriter) WriteFile(name, contentType string, r io.Reader) (location
 	return fw.MediaURL.ResolveReference(relURL).String(), nil
 }
 
+// RemoveFile deletes the file that was written to location. Locations that are
+// not in the media directory are ignored.
+func (fw *FileWriter) RemoveFile(location string) error {
+	p, ok := fw.path(location)
+	if !ok {
+		return nil
+	}
+
+	if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	slog.Info("removed file", slog.String("path", p))
+
+	return nil
+}
+
+// Size returns the size of the file that was written to location, if it is in
+// the media directory.
+func (fw *FileWriter) Size(location string) (int64, bool) {
+	p, ok := fw.path(location)
+	if !ok {
+		return 0, false
+	}
+
+	info, err := os.Stat(p)
+	if err != nil {
+		return 0, false
+	}
+
+	return info.Size(), true
+}
+
+// path returns the path of the file in the media directory for location.
+func (fw *FileWriter) path(location string) (string, bool) {
+	if fw.MediaDir == "" || fw.MediaURL == nil {
+		return "", false
+	}
+
+	name, ok := strings.CutPrefix(location, fw.MediaURL.String())
+	if !ok || name == "" || strings.ContainsAny(name, `/\`) || name == ".." {
+		return "", false
+	}
+
+	return path.Join(fw.MediaDir, name), true
+}
+
+// A MediaFile is a file that has been written to the media directory.
+type MediaFile struct {
+	URL         string `json:"url"`
+	Name        string `json:"name"`
+	ContentType string `json:"content-type,omitempty"`
+	Size        int64  `json:"size"`
+}
+
+// Manifest lists the files in the media directory.
+func (fw *FileWriter) Manifest() ([]MediaFile, error) {
+	entries, err := os.ReadDir(fw.MediaDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []MediaFile
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+
+		relURL, _ := url.Parse(entry.Name())
+		files = append(files, MediaFile{
+			URL:         fw.MediaURL.ResolveReference(relURL).String(),
+			Name:        entry.Name(),
+			ContentType: mime.TypeByExtension(path.Ext(entry.Name())),
+			Size:        info.Size(),
+		})
+	}
+
+	return files, nil
+}
+
 func extension(contentType, filename string) string {
 	ext := strings.ToLower(path.Ext(filename))
 	if len(ext) > 0 {
@@ -1,6 +1,8 @@
 package blog
 
 import (
+	"net/url"
+	"strings"
 	"testing"
 
 	"hawx.me/code/assert"
@@ -39,3 +41,22 @@ func TestExtension(t *testing.T) {
 		})
 	}
 }
+
+func TestManifest(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+	mediaURL, _ := url.Parse("http://media.example.com/")
+	fw := &FileWriter{MediaDir: dir, MediaURL: mediaURL}
+
+	location, err := fw.WriteFile("a.png", "image/png", strings.NewReader("hello"))
+	assert.Nil(err)
+
+	files, err := fw.Manifest()
+	assert.Nil(err)
+	if assert.Len(files, 1) {
+		assert.Equal(location, files[0].URL)
+		assert.Equal("image/png", files[0].ContentType)
+		assert.Equal(int64(5), files[0].Size)
+	}
+}
@@ -5,6 +5,7 @@ import (
 
 	"hawx.me/code/assert"
 	"hawx.me/code/numbersix"
+	"hawx.me/code/tally-ho/internal/page"
 )
 
 func TestGroupLikesJustPosts(t *testing.T) {
@@ -23,7 +24,7 @@ func TestGroupLikesJustPosts(t *testing.T) {
 		},
 	}
 
-	grouped := groupLikes(posts)
+	grouped := groupLikes(page.Context{}.WithPath("/"), posts)
 
 	if assert.Len(t, grouped, 2) {
 		assert.Equal(t, GroupedPosts{
@@ -63,7 +64,7 @@ func TestGroupLikesJustLikes(t *testing.T) {
 		},
 	}
 
-	grouped := groupLikes(posts)
+	grouped := groupLikes(page.Context{}.WithPath("/"), posts)
 
 	if assert.Len(t, grouped, 2) {
 		assert.Equal(t, GroupedPosts{
@@ -129,7 +130,7 @@ func TestGroupLikesMixed(t *testing.T) {
 		},
 	}
 
-	grouped := groupLikes(posts)
+	grouped := groupLikes(page.Context{}.WithPath("/"), posts)
 
 	if assert.Len(t, grouped, 4) {
 		assert.Equal(t, GroupedPosts{
@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	_ "github.com/mattn/go-sqlite3"
 	"hawx.me/code/assert"
 )
 
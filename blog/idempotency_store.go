@@ -0,0 +1,68 @@
+package blog
+
+import (
+	"database/sql"
+	"time"
+)
+
+// IdempotencyStore remembers the location of entries that have been created,
+// and a hash of the request that created them, so that a repeated create
+// request can be answered without making another.
+type IdempotencyStore struct {
+	db    *sql.DB
+	table string
+}
+
+// NewIdempotencyStore creates a store using a table named with prefix, which
+// should match the TablePrefix of the blog it is used with.
+func NewIdempotencyStore(db *sql.DB, prefix string) (*IdempotencyStore, error) {
+	s := &IdempotencyStore{db: db, table: prefix + "idempotency_keys"}
+	return s, s.init()
+}
+
+func (s *IdempotencyStore) init() error {
+	_, err := s.db.Exec(`CREATE TABLE IF NOT EXISTS ` + s.table + ` (
+    Key       TEXT PRIMARY KEY,
+    Hash      TEXT,
+    Location  TEXT,
+    ExpiresAt DATETIME
+  );`)
+
+	return err
+}
+
+// Location returns the location and request hash recorded for key, if it has
+// not expired.
+func (s *IdempotencyStore) Location(key string) (location, hash string, ok bool, err error) {
+	err = s.db.QueryRow(`SELECT Location, Hash FROM `+s.table+` WHERE Key = ? AND ExpiresAt > ?`,
+		key,
+		time.Now()).Scan(&location, &hash)
+
+	if err == sql.ErrNoRows {
+		return "", "", false, nil
+	}
+	if err != nil {
+		return "", "", false, err
+	}
+
+	return location, hash, true, nil
+}
+
+// Remember records location and the request hash for key until expiresAt.
+func (s *IdempotencyStore) Remember(key, hash, location string, expiresAt time.Time) error {
+	_, err := s.db.Exec(`
+    DELETE FROM `+s.table+`
+      WHERE Key = ?
+      OR ExpiresAt < ?;
+
+    INSERT INTO `+s.table+`(Key, Hash, Location, ExpiresAt)
+      VALUES (?, ?, ?, ?);`,
+		key,
+		time.Now(),
+		key,
+		hash,
+		location,
+		expiresAt)
+
+	return err
+}
@@ -0,0 +1,69 @@
+package blog
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"hawx.me/code/assert"
+)
+
+func TestIdempotencyStore(t *testing.T) {
+	assert := assert.New(t)
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	assert.Nil(err)
+
+	store, err := NewIdempotencyStore(db, "")
+	assert.Nil(err)
+
+	_, _, ok, err := store.Location("key")
+	assert.Nil(err)
+	assert.False(ok)
+
+	err = store.Remember("key", "hash", "http://example.com/1", time.Now().Add(5*time.Second))
+	assert.Nil(err)
+
+	location, hash, ok, err := store.Location("key")
+	assert.Nil(err)
+	assert.True(ok)
+	assert.Equal("http://example.com/1", location)
+	assert.Equal("hash", hash)
+}
+
+func TestIdempotencyStoreWhenExpired(t *testing.T) {
+	assert := assert.New(t)
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	assert.Nil(err)
+
+	store, err := NewIdempotencyStore(db, "")
+	assert.Nil(err)
+
+	err = store.Remember("key", "hash", "http://example.com/1", time.Now().Add(-5*time.Second))
+	assert.Nil(err)
+
+	_, _, ok, err := store.Location("key")
+	assert.Nil(err)
+	assert.False(ok)
+}
+
+func TestIdempotencyStoreWithPrefix(t *testing.T) {
+	assert := assert.New(t)
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	assert.Nil(err)
+
+	one, err := NewIdempotencyStore(db, "one_")
+	assert.Nil(err)
+	two, err := NewIdempotencyStore(db, "two_")
+	assert.Nil(err)
+
+	err = one.Remember("key", "hash", "http://one.example.com/1", time.Now().Add(5*time.Second))
+	assert.Nil(err)
+
+	_, _, ok, err := two.Location("key")
+	assert.Nil(err)
+	assert.False(ok)
+}
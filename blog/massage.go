@@ -4,11 +4,13 @@ import (
 	"log/slog"
 	"net/url"
 	"regexp"
-	"strings"
+	"slices"
+	"strconv"
 	"time"
 
 	"github.com/google/uuid"
 	"hawx.me/code/tally-ho/internal/mfutil"
+	"hawx.me/code/tally-ho/internal/posttype"
 	"mvdan.cc/xurls/v2"
 )
 
@@ -17,6 +19,7 @@ var citeable = map[string]string{
 	"reply":    "in-reply-to",
 	"repost":   "repost-of",
 	"bookmark": "bookmark-of",
+	"review":   "item",
 }
 
 // massage will do all of the magic to the data to make it nicer. It should be
@@ -27,20 +30,40 @@ func (b *Blog) massage(data map[string][]any) {
 		uid := uuid.New().String()
 		data["uid"] = []any{uid}
 	}
+
+	published := time.Now().UTC()
+	if len(data["published"]) > 0 {
+		if t, err := mfutil.ParseDate(data["published"][0].(string)); err == nil {
+			published = t.UTC()
+		}
+	}
+	data["published"] = []any{published.Format(time.RFC3339)}
+
+	kind := posttype.Discover(data)
+
 	if len(data["url"]) == 0 {
-		relativeURL, _ := url.Parse("entry/" + data["uid"][0].(string))
+		relativeURL, _ := url.Parse(published.Format("2006/01/02/") + slugFor(data, kind))
 		location := b.config.BaseURL.ResolveReference(relativeURL).String()
 
 		data["url"] = []any{location}
 	}
 
-	if len(data["published"]) == 0 {
-		data["published"] = []any{time.Now().UTC().Format(time.RFC3339)}
-	} else {
-		data["published"] = []any{parseDate(data["published"][0].(string)).UTC().Format(time.RFC3339)}
-	}
+	// aliases are other urls the entry was known by, which may be given
+	// relative to the blog, so that requests for them can be redirected
+	if aliases, ok := data["hx-aliases"]; ok {
+		var absolute []any
+		for _, alias := range searchStrings(aliases) {
+			if alias = b.absoluteURL(alias); alias != data["url"][0] && !slices.Contains(absolute, any(alias)) {
+				absolute = append(absolute, alias)
+			}
+		}
 
-	kind := postTypeDiscovery(data)
+		if len(absolute) > 0 {
+			data["hx-aliases"] = absolute
+		} else {
+			delete(data, "hx-aliases")
+		}
+	}
 
 	for k, v := range citeable {
 		if kind == k {
@@ -60,9 +83,55 @@ func (b *Blog) massage(data map[string][]any) {
 		}
 	}
 
+	// ratings may be given as numbers in JSON, but are stored as strings like
+	// any other value
+	for _, key := range []string{"rating", "best", "worst"} {
+		for i, value := range data[key] {
+			if f, ok := value.(float64); ok {
+				data[key][i] = strconv.FormatFloat(f, 'f', -1, 64)
+			}
+		}
+	}
+
+	// alt text can be given separately to the photos it describes, in the same
+	// order, so store it with each photo
+	if alts, ok := data["mp-photo-alt"]; ok {
+		for i, alt := range alts {
+			if i >= len(data["photo"]) {
+				break
+			}
+
+			if s, ok := data["photo"][i].(string); ok && alt != "" {
+				data["photo"][i] = map[string]any{"value": s, "alt": alt}
+			}
+		}
+
+		delete(data, "mp-photo-alt")
+	}
+
 	// kind could be changed by an update, so this is fine
 	data["hx-kind"] = []any{kind}
 
+	// similarly the status can be changed, so drafts can be published
+	if status, ok := mfutil.Get(data, "post-status").(string); ok && status == "draft" {
+		data["hx-draft"] = []any{true}
+	} else {
+		delete(data, "hx-draft")
+	}
+
+	// as can the visibility, where private entries are also unlisted
+	switch mfutil.Get(data, "visibility") {
+	case "private":
+		data["hx-unlisted"] = []any{true}
+		data["hx-private"] = []any{true}
+	case "unlisted":
+		data["hx-unlisted"] = []any{true}
+		delete(data, "hx-private")
+	default:
+		delete(data, "hx-unlisted")
+		delete(data, "hx-private")
+	}
+
 	if content, ok := data["content"]; ok && len(content) > 0 {
 		// safe because it only attempts to autolink when content is a string
 		if s, ok := content[0].(string); ok {
@@ -95,94 +164,3 @@ func (b *Blog) massage(data map[string][]any) {
 		}
 	}
 }
-
-func postTypeDiscovery(data map[string][]any) string {
-	if rsvp, ok := data["rsvp"]; ok && len(rsvp) > 0 && (rsvp[0] == "yes" || rsvp[0] == "no" || rsvp[0] == "maybe") {
-		return "rsvp"
-	}
-
-	if u, ok := data["in-reply-to"]; ok && len(u) > 0 {
-		return "reply"
-	}
-
-	if u, ok := data["repost-of"]; ok && len(u) > 0 {
-		return "repost"
-	}
-
-	if u, ok := data["like-of"]; ok && len(u) > 0 {
-		return "like"
-	}
-
-	if u, ok := data["bookmark-of"]; ok && len(u) > 0 {
-		return "bookmark"
-	}
-
-	if u, ok := data["video"]; ok && len(u) > 0 {
-		return "video"
-	}
-
-	if u, ok := data["photo"]; ok && len(u) > 0 {
-		return "photo"
-	}
-
-	if u, ok := data["read-of"]; ok && len(u) > 0 {
-		return "read"
-	}
-
-	if u, ok := data["drank"]; ok && len(u) > 0 {
-		return "drank"
-	}
-
-	if u, ok := data["checkin"]; ok && len(u) > 0 {
-		return "checkin"
-	}
-
-	// I know the algorithm https://indieweb.org/post-type-discovery does more
-	// than this, that is for another time
-	if n, ok := data["name"]; ok && len(n) > 0 {
-		return "article"
-	}
-
-	return "note"
-}
-
-func parseDate(s string) time.Time {
-	var layouts = []string{
-		time.RFC3339,
-		time.RFC3339Nano,
-		"2006-01-02T15:04:05-0700",
-		"2006-01-02T15:04-0700",
-		"2006-01-02 15:04:05-0700",
-		"2006-01-02 15:04-0700",
-		"Mon, _2 Jan 2006 15:04:05 MST",
-		"Mon, _2 Jan 2006 15:04:05 -0700",
-		time.ANSIC,
-		time.UnixDate,
-		time.RubyDate,
-		time.RFC822,
-		time.RFC822Z,
-		time.RFC850,
-		time.RFC1123,
-		time.RFC1123Z,
-		"Mon, 2, Jan 2006 15:4",
-		"02 Jan 2006 15:04:05 MST",
-	}
-
-	var t time.Time
-	var err error
-	s = strings.TrimSpace(s)
-
-	for _, layout := range layouts {
-		t, err = time.Parse(layout, s)
-		if !t.IsZero() {
-			break
-		}
-	}
-
-	// give up
-	if err != nil {
-		t = time.Now()
-	}
-
-	return t
-}
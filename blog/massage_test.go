@@ -27,7 +27,7 @@ func TestMassage(t *testing.T) {
 			in: map[string][]interface{}{},
 			fn: func(assert Assert, data map[string][]interface{}) {
 				assert(data["uid"][0].(string)).NotEmpty()
-				assert(data["url"][0].(string)).Equal("http://example.com/entry/" + data["uid"][0].(string))
+				assert(data["url"][0].(string)).Equal("http://example.com/" + time.Now().UTC().Format("2006/01/02") + "/note")
 
 				published, _ := time.Parse(time.RFC3339, data["published"][0].(string))
 				assert(published).WithinDuration(time.Now(), time.Second)
@@ -44,6 +44,144 @@ func TestMassage(t *testing.T) {
 				assert(published).Equal(time.Date(2020, time.October, 1, 12, 03, 1, 0, time.UTC))
 			},
 		},
+		"mp-slug": {
+			in: map[string][]interface{}{
+				"published": {"2020-10-01T12:03:01Z"},
+				"name":      {"A post"},
+				"mp-slug":   {"My Slug"},
+			},
+			fn: func(assert Assert, data map[string][]interface{}) {
+				assert(data["url"][0]).Equal("http://example.com/2020/10/01/my-slug")
+			},
+		},
+		"slug-from-name": {
+			in: map[string][]interface{}{
+				"published": {"2020-10-01T12:03:01Z"},
+				"name":      {"What's new in Go?"},
+			},
+			fn: func(assert Assert, data map[string][]interface{}) {
+				assert(data["url"][0]).Equal("http://example.com/2020/10/01/whats-new-in-go")
+			},
+		},
+		"event": {
+			in: map[string][]interface{}{
+				"h":         {"event"},
+				"published": {"2020-10-01T12:03:01Z"},
+				"name":      {"Go meetup"},
+				"start":     {"2020-10-08T18:30:00+01:00"},
+			},
+			fn: func(assert Assert, data map[string][]interface{}) {
+				assert(data["hx-kind"]).Equal([]interface{}{"event"})
+				assert(data["url"][0]).Equal("http://example.com/2020/10/01/go-meetup")
+				assert(data["start"]).Equal([]interface{}{"2020-10-08T18:30:00+01:00"})
+			},
+		},
+		"review": {
+			in: map[string][]interface{}{
+				"h": {"review"},
+				"item": {map[string]any{
+					"type":       []any{"h-product"},
+					"properties": map[string]any{"name": []any{"A kettle"}},
+				}},
+				"rating": {float64(4)},
+				"best":   {float64(5)},
+			},
+			fn: func(assert Assert, data map[string][]interface{}) {
+				assert(data["hx-kind"]).Equal([]interface{}{"review"})
+				assert(data["rating"]).Equal([]interface{}{"4"})
+				assert(data["best"]).Equal([]interface{}{"5"})
+			},
+		},
+		"slug-from-content": {
+			in: map[string][]interface{}{
+				"published": {"2020-10-01T12:03:01Z"},
+				"content":   {"This is a note that has quite a lot of words in it."},
+			},
+			fn: func(assert Assert, data map[string][]interface{}) {
+				assert(data["url"][0]).Equal("http://example.com/2020/10/01/this-is-a-note-that-has")
+			},
+		},
+		"photo-alt": {
+			in: map[string][]interface{}{
+				"photo":        {"http://example.com/a.jpg", "http://example.com/b.jpg", "http://example.com/c.jpg"},
+				"mp-photo-alt": {"a cat", ""},
+			},
+			fn: func(assert Assert, data map[string][]interface{}) {
+				assert(data["photo"]).Equal([]interface{}{
+					map[string]any{"value": "http://example.com/a.jpg", "alt": "a cat"},
+					"http://example.com/b.jpg",
+					"http://example.com/c.jpg",
+				})
+				assert(data["mp-photo-alt"]).Nil()
+			},
+		},
+		"photo-alt-blank-first": {
+			in: map[string][]interface{}{
+				"photo":        {"http://example.com/a.jpg", "http://example.com/b.jpg"},
+				"mp-photo-alt": {"", "a dog"},
+			},
+			fn: func(assert Assert, data map[string][]interface{}) {
+				assert(data["photo"]).Equal([]interface{}{
+					"http://example.com/a.jpg",
+					map[string]any{"value": "http://example.com/b.jpg", "alt": "a dog"},
+				})
+			},
+		},
+		"draft": {
+			in: map[string][]interface{}{
+				"post-status": {"draft"},
+			},
+			fn: func(assert Assert, data map[string][]interface{}) {
+				assert(data["hx-draft"]).Equal([]interface{}{true})
+			},
+		},
+		"published-draft": {
+			in: map[string][]interface{}{
+				"post-status": {"published"},
+				"hx-draft":    {true},
+			},
+			fn: func(assert Assert, data map[string][]interface{}) {
+				assert(data["hx-draft"]).Nil()
+			},
+		},
+		"unlisted": {
+			in: map[string][]interface{}{
+				"visibility": {"unlisted"},
+			},
+			fn: func(assert Assert, data map[string][]interface{}) {
+				assert(data["hx-unlisted"]).Equal([]interface{}{true})
+				assert(data["hx-private"]).Nil()
+			},
+		},
+		"private": {
+			in: map[string][]interface{}{
+				"visibility": {"private"},
+			},
+			fn: func(assert Assert, data map[string][]interface{}) {
+				assert(data["hx-unlisted"]).Equal([]interface{}{true})
+				assert(data["hx-private"]).Equal([]interface{}{true})
+			},
+		},
+		"made-public": {
+			in: map[string][]interface{}{
+				"visibility":  {"public"},
+				"hx-unlisted": {true},
+				"hx-private":  {true},
+			},
+			fn: func(assert Assert, data map[string][]interface{}) {
+				assert(data["hx-unlisted"]).Nil()
+				assert(data["hx-private"]).Nil()
+			},
+		},
+		"existing-url": {
+			in: map[string][]interface{}{
+				"url":  {"http://example.com/entry/1"},
+				"name": {"A post"},
+			},
+			fn: func(assert Assert, data map[string][]interface{}) {
+				assert(data["url"][0]).Equal("http://example.com/entry/1")
+			},
+		},
 	}
 
 	for name, tc := range testCases {
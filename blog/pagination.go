@@ -0,0 +1,261 @@
+package blog
+
+import (
+	"cmp"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"hawx.me/code/numbersix"
+	"hawx.me/code/route"
+)
+
+// defaultPageSize is the number of entries, or mentions, on each page of a list
+// when Config.PageSize is not set.
+const defaultPageSize = 25
+
+func (b *Blog) pageSize() int {
+	if b.config.PageSize > 0 {
+		return b.config.PageSize
+	}
+
+	return defaultPageSize
+}
+
+// ErrInvalidCursor is returned when a cursor given for a page can not be read.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// A cursor is a position in a list. Items are ordered by the time they were
+// published, and then by subject so that those published in the same second
+// are not skipped.
+type cursor struct {
+	published string
+	subject   string
+}
+
+// parseCursor reads a cursor given as published_subject, or just published to
+// start from a time.
+func parseCursor(s string) (cursor, bool) {
+	published, subject, _ := strings.Cut(s, "_")
+	if _, err := time.Parse(time.RFC3339, published); err != nil {
+		return cursor{}, false
+	}
+
+	return cursor{published: published, subject: subject}, true
+}
+
+// timeCursor returns a cursor for the time, so that a list starts with the
+// items published either side of it.
+func timeCursor(t time.Time) cursor {
+	return cursor{published: t.UTC().Format(time.RFC3339)}
+}
+
+func cursorOf(group numbersix.Group) cursor {
+	return cursor{published: publishedOf(group), subject: group.Subject}
+}
+
+func (c cursor) String() string {
+	if c.subject == "" {
+		return c.published
+	}
+
+	return c.published + "_" + c.subject
+}
+
+// A listPage is a page of entries, or mentions, newest first. Newer and Older
+// are the cursors for the pages either side, and are empty when there is
+// nothing more in that direction.
+type listPage struct {
+	Items []numbersix.Group
+	Newer string
+	Older string
+}
+
+// listPage returns the page of items in db matching query that come before the
+// cursor, or if after is given those that come after it. When neither is given
+// the latest items are returned.
+func (b *Blog) listPage(db *numbersix.DB, query func() *numbersix.Query, before, after string) (listPage, error) {
+	return readPage(db, query, before, after, b.pageSize())
+}
+
+// readPage returns a listPage with at most size items.
+func readPage(db *numbersix.DB, query func() *numbersix.Query, before, after string, size int) (listPage, error) {
+	var p listPage
+
+	if c, ok := parseCursor(after); ok {
+		// these are returned oldest first
+		groups, err := itemsAfter(db, query, c, size+1)
+		if err != nil {
+			return p, err
+		}
+
+		if len(groups) > size {
+			groups = groups[:size]
+			p.Newer = cursorOf(groups[size-1]).String()
+		}
+		slices.Reverse(groups)
+		p.Items = groups
+
+		if len(groups) > 0 {
+			oldest := cursorOf(groups[len(groups)-1])
+			if older, err := itemsBefore(db, query, oldest, 1); err != nil {
+				return p, err
+			} else if len(older) > 0 {
+				p.Older = oldest.String()
+			}
+		}
+
+		return p, nil
+	}
+
+	c, ok := parseCursor(before)
+	latest := !ok
+	if latest {
+		c = cursor{published: time.Now().UTC().Format(time.RFC3339)}
+	}
+
+	groups, err := itemsBefore(db, query, c, size+1)
+	if err != nil {
+		return p, err
+	}
+
+	if len(groups) > size {
+		groups = groups[:size]
+		p.Older = cursorOf(groups[size-1]).String()
+	}
+	p.Items = groups
+
+	if !latest && len(groups) > 0 {
+		newest := cursorOf(groups[0])
+		if newer, err := itemsAfter(db, query, newest, 1); err != nil {
+			return p, err
+		} else if len(newer) > 0 {
+			p.Newer = newest.String()
+		}
+	}
+
+	return p, nil
+}
+
+// itemsBefore returns, newest first, the n items matching query that come
+// before the cursor.
+func itemsBefore(db *numbersix.DB, query func() *numbersix.Query, c cursor, n int) ([]numbersix.Group, error) {
+	groups, err := readGroups(db, func() *numbersix.Query {
+		return query().Before("published", c.published)
+	}, n)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.subject != "" {
+		ties, err := db.List(query().Where("published", c.published))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, group := range numbersix.Grouped(ties) {
+			if group.Subject < c.subject {
+				groups = append(groups, group)
+			}
+		}
+	}
+
+	slices.SortFunc(groups, func(a, b numbersix.Group) int {
+		return compareItems(b, a)
+	})
+
+	return groups[:min(n, len(groups))], nil
+}
+
+// itemsAfter returns, oldest first, the n items matching query that come after
+// the cursor.
+func itemsAfter(db *numbersix.DB, query func() *numbersix.Query, c cursor, n int) ([]numbersix.Group, error) {
+	groups, err := readGroups(db, func() *numbersix.Query {
+		return query().After("published", c.published)
+	}, n)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.subject != "" {
+		ties, err := db.List(query().Where("published", c.published))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, group := range numbersix.Grouped(ties) {
+			if group.Subject > c.subject {
+				groups = append(groups, group)
+			}
+		}
+	}
+
+	slices.SortFunc(groups, compareItems)
+
+	return groups[:min(n, len(groups))], nil
+}
+
+func compareItems(a, b numbersix.Group) int {
+	return cmp.Or(
+		cmp.Compare(publishedOf(a), publishedOf(b)),
+		cmp.Compare(a.Subject, b.Subject),
+	)
+}
+
+// readGroups returns at least the first n items matching query, in the order
+// the query sorts them, reading a limited number of triples at a time. Unless
+// everything was read, the last item read is left out as it may be missing
+// properties, along with those published in the same second as the item
+// before it, as there may be more of those that have not been read.
+func readGroups(db *numbersix.DB, query func() *numbersix.Query, n int) ([]numbersix.Group, error) {
+	for limit := (n + 1) * 16; ; limit *= 2 {
+		triples, err := db.List(query().Limit(limit))
+		if err != nil {
+			return nil, err
+		}
+
+		groups := numbersix.Grouped(triples)
+		if len(triples) < limit {
+			return groups, nil
+		}
+
+		groups = groups[:len(groups)-1]
+		if len(groups) > 0 {
+			cut := publishedOf(groups[len(groups)-1])
+			for len(groups) > 0 && publishedOf(groups[len(groups)-1]) == cut {
+				groups = groups[:len(groups)-1]
+			}
+		}
+
+		if len(groups) >= n {
+			return groups, nil
+		}
+	}
+}
+
+func publishedOf(group numbersix.Group) string {
+	published, _ := group.Properties["published"][0].(string)
+	return published
+}
+
+// listCursor returns the cursors given for a list, either in the path or as
+// parameters. Those that are not cursors are ignored.
+func listCursor(r *http.Request) (before, after string) {
+	vars := route.Vars(r)
+
+	before, after = vars["before"], vars["after"]
+	if before == "" && after == "" {
+		before, after = r.FormValue("before"), r.FormValue("after")
+	}
+
+	if _, ok := parseCursor(before); !ok {
+		before = ""
+	}
+	if _, ok := parseCursor(after); !ok {
+		after = ""
+	}
+
+	return before, after
+}
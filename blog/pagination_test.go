@@ -0,0 +1,204 @@
+package blog
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"hawx.me/code/assert"
+	"hawx.me/code/numbersix"
+	"hawx.me/code/tally-ho/internal/page"
+)
+
+func TestListPage(t *testing.T) {
+	assert := assert.New(t)
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	assert.Nil(err)
+
+	entries, err := numbersix.For(db, "entries")
+	assert.Nil(err)
+
+	b := &Blog{config: Config{PageSize: 2}, entries: entries}
+
+	// each entry has many triples, so that limiting them would split entries
+	for i := range 5 {
+		assert.Nil(entries.SetProperties(fmt.Sprint(i), map[string][]any{
+			"uid":       {fmt.Sprint(i)},
+			"published": {fmt.Sprintf("2020-10-01T12:%02d:00Z", i)},
+			"category":  {"a", "b", "c", "d", "e"},
+		}))
+	}
+
+	uids := func(p listPage) []string {
+		var uids []string
+		for _, item := range p.Items {
+			assert.Len(item.Properties["category"], 5)
+			uids = append(uids, item.Subject)
+		}
+		return uids
+	}
+
+	latest, err := b.listPage(entries, listed, "", "")
+	assert.Nil(err)
+	assert.Equal([]string{"4", "3"}, uids(latest))
+	assert.Equal("", latest.Newer)
+	assert.Equal("2020-10-01T12:03:00Z_3", latest.Older)
+
+	older, err := b.listPage(entries, listed, latest.Older, "")
+	assert.Nil(err)
+	assert.Equal([]string{"2", "1"}, uids(older))
+	assert.Equal("2020-10-01T12:02:00Z_2", older.Newer)
+	assert.Equal("2020-10-01T12:01:00Z_1", older.Older)
+
+	oldest, err := b.listPage(entries, listed, older.Older, "")
+	assert.Nil(err)
+	assert.Equal([]string{"0"}, uids(oldest))
+	assert.Equal("2020-10-01T12:00:00Z_0", oldest.Newer)
+	assert.Equal("", oldest.Older)
+
+	newer, err := b.listPage(entries, listed, "", oldest.Newer)
+	assert.Nil(err)
+	assert.Equal([]string{"2", "1"}, uids(newer))
+	assert.Equal("2020-10-01T12:02:00Z_2", newer.Newer)
+	assert.Equal("2020-10-01T12:01:00Z_1", newer.Older)
+
+	newest, err := b.listPage(entries, listed, "", newer.Newer)
+	assert.Nil(err)
+	assert.Equal([]string{"4", "3"}, uids(newest))
+	assert.Equal("", newest.Newer)
+	assert.Equal("2020-10-01T12:03:00Z_3", newest.Older)
+}
+
+func TestListPageTies(t *testing.T) {
+	assert := assert.New(t)
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	assert.Nil(err)
+
+	entries, err := numbersix.For(db, "entries")
+	assert.Nil(err)
+
+	b := &Blog{config: Config{PageSize: 2}, entries: entries}
+
+	// entries are published to the second, so many can be published at once
+	for i := range 5 {
+		assert.Nil(entries.SetProperties(fmt.Sprint(i), map[string][]any{
+			"uid":       {fmt.Sprint(i)},
+			"published": {"2020-10-01T12:00:00Z"},
+		}))
+	}
+	assert.Nil(entries.SetProperties("5", map[string][]any{
+		"uid":       {"5"},
+		"published": {"2020-10-01T11:00:00Z"},
+	}))
+
+	var seen []string
+	p, err := b.listPage(entries, listed, "", "")
+	for {
+		assert.Nil(err)
+		for _, item := range p.Items {
+			seen = append(seen, item.Subject)
+		}
+		if p.Older == "" {
+			break
+		}
+		p, err = b.listPage(entries, listed, p.Older, "")
+	}
+	assert.Equal([]string{"4", "3", "2", "1", "0", "5"}, seen)
+
+	seen = nil
+	p, err = b.listPage(entries, listed, "", "2020-10-01T11:00:00Z_5")
+	for {
+		assert.Nil(err)
+		seen = append(uidsOf(p.Items), seen...)
+		if p.Newer == "" {
+			break
+		}
+		p, err = b.listPage(entries, listed, "", p.Newer)
+	}
+	assert.Equal([]string{"4", "3", "2", "1", "0"}, seen)
+
+	// a time alone leaves out everything published at that time
+	p, err = b.listPage(entries, listed, "2020-10-01T12:00:00Z", "")
+	assert.Nil(err)
+	assert.Equal([]string{"5"}, uidsOf(p.Items))
+}
+
+func uidsOf(groups []numbersix.Group) []string {
+	var uids []string
+	for _, group := range groups {
+		uids = append(uids, group.Subject)
+	}
+	return uids
+}
+
+func TestReadGroups(t *testing.T) {
+	assert := assert.New(t)
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	assert.Nil(err)
+
+	entries, err := numbersix.For(db, "entries")
+	assert.Nil(err)
+
+	// enough entries, with enough triples, that they are read more than once
+	for i := range 50 {
+		assert.Nil(entries.SetProperties(fmt.Sprintf("%02d", i), map[string][]any{
+			"published": {fmt.Sprintf("2020-10-01T12:%02d:00Z", i/2)},
+			"category":  {"a", "b", "c", "d", "e"},
+		}))
+	}
+
+	groups, err := readGroups(entries, func() *numbersix.Query {
+		return listed().Before("published", "2020-10-02T00:00:00Z")
+	}, 10)
+	assert.Nil(err)
+	assert.True(len(groups) >= 10)
+	for _, group := range groups {
+		assert.Len(group.Properties["category"], 5)
+	}
+	assert.Equal(0, len(groups)%2)
+}
+
+func TestListPageLinks(t *testing.T) {
+	assert := assert.New(t)
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	assert.Nil(err)
+
+	entries, err := numbersix.For(db, "entries")
+	assert.Nil(err)
+
+	mentions, err := numbersix.For(db, "mentions")
+	assert.Nil(err)
+
+	baseURL, _ := url.Parse("http://localhost:8080/")
+	b := &Blog{
+		config:   Config{BaseURL: baseURL, PageSize: 1, HubURL: "http://localhost:8080/-/hub"},
+		pageCtx:  page.Context{Name: "test"}.WithPath("/"),
+		entries:  entries,
+		mentions: mentions,
+	}
+
+	for i := range 3 {
+		assert.Nil(entries.SetProperties(fmt.Sprint(i), map[string][]any{
+			"uid":       {fmt.Sprint(i)},
+			"url":       {fmt.Sprintf("http://localhost:8080/%d", i)},
+			"published": {fmt.Sprintf("2020-10-01T12:%02d:00Z", i)},
+			"hx-kind":   {"note"},
+			"content":   {"hey"},
+		}))
+	}
+
+	w := httptest.NewRecorder()
+	b.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/kind/note?before=2020-10-01T12:02:00Z_2", nil))
+
+	assert.Equal(200, w.Code)
+	assert.Equal([]string{
+		`<http://localhost:8080/kind/note/?after=2020-10-01T12%3A01%3A00Z_1>; rel="prev"`,
+		`<http://localhost:8080/kind/note/?before=2020-10-01T12%3A01%3A00Z_1>; rel="next"`,
+	}, w.Header().Values("Link"))
+}
@@ -0,0 +1,188 @@
+package blog
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"hawx.me/code/numbersix"
+)
+
+// A Redirect sends requests for a url that no longer exists, From, to the url
+// that replaced it, To.
+type Redirect struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type redirectStore struct {
+	db    *sql.DB
+	table string
+}
+
+func newRedirectStore(db *sql.DB, prefix string) (*redirectStore, error) {
+	s := &redirectStore{db: db, table: prefix + "redirects"}
+	return s, s.init()
+}
+
+func (s *redirectStore) init() error {
+	_, err := s.db.Exec(`CREATE TABLE IF NOT EXISTS ` + s.table + ` (
+    FromURL TEXT PRIMARY KEY,
+    ToURL   TEXT
+  );`)
+
+	return err
+}
+
+// Set redirects from to to, replacing any existing redirect from it.
+func (s *redirectStore) Set(from, to string) error {
+	_, err := s.db.Exec(`INSERT OR REPLACE INTO `+s.table+`(FromURL, ToURL) VALUES (?, ?);`,
+		from,
+		to)
+
+	return err
+}
+
+// Remove stops redirecting from.
+func (s *redirectStore) Remove(from string) error {
+	result, err := s.db.Exec(`DELETE FROM `+s.table+` WHERE FromURL = ?;`,
+		from)
+	if err != nil {
+		return err
+	}
+
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("no redirect from %s: %w", from, ErrNotFound)
+	}
+
+	return nil
+}
+
+// Get returns where from redirects to.
+func (s *redirectStore) Get(from string) (string, error) {
+	var to string
+	err := s.db.QueryRow(`SELECT ToURL FROM `+s.table+` WHERE FromURL = ?`,
+		from).Scan(&to)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return to, fmt.Errorf("no redirect from %s: %w", from, ErrNotFound)
+	}
+
+	return to, err
+}
+
+// List returns all of the redirects, ordered by the url they are from.
+func (s *redirectStore) List() ([]Redirect, error) {
+	rows, err := s.db.Query(`SELECT FromURL, ToURL FROM ` + s.table + ` ORDER BY FromURL`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var redirects []Redirect
+	for rows.Next() {
+		var redirect Redirect
+		if err := rows.Scan(&redirect.From, &redirect.To); err != nil {
+			return nil, err
+		}
+
+		redirects = append(redirects, redirect)
+	}
+
+	return redirects, rows.Err()
+}
+
+// AddRedirect sends requests for from to to. Either can be given relative to
+// the blog.
+func (b *Blog) AddRedirect(from, to string) error {
+	from, to = b.absoluteURL(from), b.absoluteURL(to)
+	if from == to {
+		return errors.New("can not redirect a url to itself")
+	}
+
+	if err := b.redirects.Set(from, to); err != nil {
+		return err
+	}
+
+	b.rebuildRedirect(from)
+	return nil
+}
+
+// RemoveRedirect stops sending requests for from elsewhere.
+func (b *Blog) RemoveRedirect(from string) error {
+	from = b.absoluteURL(from)
+
+	if err := b.redirects.Remove(from); err != nil {
+		return err
+	}
+
+	b.rebuildRedirect(from)
+	return nil
+}
+
+// Redirects returns the redirects that have been added, but not the aliases of
+// entries.
+func (b *Blog) Redirects() ([]Redirect, error) {
+	return b.redirects.List()
+}
+
+// Redirect returns the url that requests for url should be sent to instead.
+// This is either the url of the entry that has url as an alias, or a url it was
+// redirected to.
+func (b *Blog) Redirect(url string) (string, error) {
+	triples, err := b.entries.List(numbersix.Where("hx-aliases", url))
+	if err != nil {
+		return "", err
+	}
+
+	if groups := numbersix.Grouped(triples); len(groups) > 0 {
+		if location, ok := groups[0].Properties["url"][0].(string); ok {
+			return location, nil
+		}
+	}
+
+	return b.redirects.Get(url)
+}
+
+// redirectingWriter replaces not found responses with a redirect, when there is
+// one for the requested url.
+type redirectingWriter struct {
+	http.ResponseWriter
+	b          *Blog
+	r          *http.Request
+	redirected bool
+}
+
+func (w *redirectingWriter) WriteHeader(code int) {
+	if code == http.StatusNotFound {
+		if location, err := w.b.Redirect(w.b.absoluteURL(strings.TrimPrefix(w.r.URL.Path, "/"))); err == nil {
+			if w.r.URL.RawQuery != "" {
+				location += "?" + w.r.URL.RawQuery
+			}
+
+			w.redirected = true
+			w.Header().Del("Content-Type")
+			w.Header().Del("X-Content-Type-Options")
+			http.Redirect(w.ResponseWriter, w.r, location, http.StatusMovedPermanently)
+			return
+		}
+	}
+
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *redirectingWriter) Write(p []byte) (int, error) {
+	if w.redirected {
+		return len(p), nil
+	}
+
+	return w.ResponseWriter.Write(p)
+}
+
+func (b *Blog) redirecting(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(&redirectingWriter{ResponseWriter: w, b: b, r: r}, r)
+	})
+}
@@ -0,0 +1,98 @@
+package blog
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"hawx.me/code/assert"
+	"hawx.me/code/tally-ho/internal/page"
+)
+
+func TestRedirectStore(t *testing.T) {
+	assert := assert.New(t)
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	assert.Nil(err)
+
+	store, err := newRedirectStore(db, "")
+	assert.Nil(err)
+
+	assert.Nil(store.Set("http://example.com/b", "http://example.com/1"))
+	assert.Nil(store.Set("http://example.com/a", "http://example.com/1"))
+	assert.Nil(store.Set("http://example.com/b", "http://example.com/2"))
+
+	to, err := store.Get("http://example.com/b")
+	assert.Nil(err)
+	assert.Equal("http://example.com/2", to)
+
+	redirects, err := store.List()
+	assert.Nil(err)
+	assert.Equal([]Redirect{
+		{From: "http://example.com/a", To: "http://example.com/1"},
+		{From: "http://example.com/b", To: "http://example.com/2"},
+	}, redirects)
+
+	assert.Nil(store.Remove("http://example.com/a"))
+	assert.True(errors.Is(store.Remove("http://example.com/a"), ErrNotFound))
+
+	_, err = store.Get("http://example.com/a")
+	assert.True(errors.Is(err, ErrNotFound))
+}
+
+func TestRedirect(t *testing.T) {
+	assert := assert.New(t)
+
+	db, err := sql.Open("sqlite3", "file:redirects?mode=memory&cache=shared")
+	assert.Nil(err)
+
+	baseURL, _ := url.Parse("http://localhost:8080/")
+	b, err := New(slog.Default(), Config{
+		Me:      "http://localhost:8080/",
+		BaseURL: baseURL,
+	}, page.Context{Name: "test"}.WithPath("/"), db, fakeHubPublisher{}, nil)
+	assert.Nil(err)
+	defer b.Close()
+
+	location, err := b.Create(map[string][]any{
+		"h":          {"entry"},
+		"content":    {"a note"},
+		"hx-aliases": {"old/note", "http://localhost:8080/posts/1.html"},
+	})
+	assert.Nil(err)
+
+	entry, err := b.Entry(location)
+	assert.Nil(err)
+	assert.Equal([]any{"http://localhost:8080/old/note", "http://localhost:8080/posts/1.html"}, entry["hx-aliases"])
+
+	assert.Nil(b.AddRedirect("feed.xml", "feed/atom"))
+	assert.NotNil(b.AddRedirect("feed/atom", "/feed/atom"))
+
+	to, err := b.Redirect("http://localhost:8080/posts/1.html")
+	assert.Nil(err)
+	assert.Equal(location, to)
+
+	handler := b.Handler()
+
+	for path, expected := range map[string]string{
+		"/old/note":        location,
+		"/posts/1.html":    location,
+		"/feed.xml?page=2": "http://localhost:8080/feed/atom?page=2",
+	} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+
+		assert.Equal(http.StatusMovedPermanently, w.Code)
+		assert.Equal(expected, w.Header().Get("Location"))
+	}
+
+	assert.Nil(b.RemoveRedirect("/feed.xml"))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/feed.xml", nil))
+	assert.Equal(http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,310 @@
+package blog
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"hawx.me/code/tally-ho/internal/page"
+)
+
+// A Revision is a snapshot of the properties of an entry, taken each time it is
+// created or updated.
+type Revision struct {
+	UID        string
+	Version    int
+	CreatedAt  time.Time
+	Properties map[string][]any
+}
+
+type revisionStore struct {
+	db    *sql.DB
+	table string
+}
+
+func newRevisionStore(db *sql.DB, prefix string) (*revisionStore, error) {
+	s := &revisionStore{db: db, table: prefix + "revisions"}
+	return s, s.init()
+}
+
+func (s *revisionStore) init() error {
+	_, err := s.db.Exec(`CREATE TABLE IF NOT EXISTS ` + s.table + ` (
+    UID        TEXT,
+    Version    INTEGER,
+    CreatedAt  DATETIME,
+    Properties TEXT,
+    PRIMARY KEY (UID, Version)
+  );`)
+
+	return err
+}
+
+// Save records data as the next revision of the entry with uid.
+func (s *revisionStore) Save(uid string, data map[string][]any) error {
+	properties, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`
+    INSERT INTO `+s.table+`(UID, Version, CreatedAt, Properties)
+      SELECT ?, COALESCE(MAX(Version), 0) + 1, ?, ?
+      FROM `+s.table+`
+      WHERE UID = ?;`,
+		uid,
+		time.Now().UTC(),
+		string(properties),
+		uid)
+
+	return err
+}
+
+// List returns the revisions of the entry with uid, oldest first.
+func (s *revisionStore) List(uid string) ([]Revision, error) {
+	rows, err := s.db.Query(`SELECT UID, Version, CreatedAt, Properties FROM `+s.table+` WHERE UID = ? ORDER BY Version`,
+		uid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions []Revision
+	for rows.Next() {
+		revision, err := scanRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		revisions = append(revisions, revision)
+	}
+
+	return revisions, rows.Err()
+}
+
+// All returns the revisions of every entry, ordered by entry then oldest first.
+func (s *revisionStore) All() ([]Revision, error) {
+	rows, err := s.db.Query(`SELECT UID, Version, CreatedAt, Properties FROM ` + s.table + ` ORDER BY UID, Version`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions []Revision
+	for rows.Next() {
+		revision, err := scanRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		revisions = append(revisions, revision)
+	}
+
+	return revisions, rows.Err()
+}
+
+// Insert records revision as it is, replacing any with the same version.
+func (s *revisionStore) Insert(revision Revision) error {
+	properties, err := json.Marshal(revision.Properties)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`INSERT OR REPLACE INTO `+s.table+`(UID, Version, CreatedAt, Properties) VALUES (?, ?, ?, ?);`,
+		revision.UID,
+		revision.Version,
+		revision.CreatedAt,
+		string(properties))
+
+	return err
+}
+
+// Get returns a single revision of the entry with uid.
+func (s *revisionStore) Get(uid string, version int) (Revision, error) {
+	revision, err := scanRevision(s.db.QueryRow(`SELECT UID, Version, CreatedAt, Properties FROM `+s.table+` WHERE UID = ? AND Version = ?`,
+		uid,
+		version))
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return revision, fmt.Errorf("no revision %d of %s: %w", version, uid, ErrNotFound)
+	}
+
+	return revision, err
+}
+
+// Remove deletes every revision of the entry with uid.
+func (s *revisionStore) Remove(uid string) error {
+	_, err := s.db.Exec(`DELETE FROM `+s.table+` WHERE UID = ?;`,
+		uid)
+
+	return err
+}
+
+func scanRevision(row interface{ Scan(...any) error }) (Revision, error) {
+	var (
+		revision   Revision
+		properties string
+	)
+
+	if err := row.Scan(&revision.UID, &revision.Version, &revision.CreatedAt, &properties); err != nil {
+		return revision, err
+	}
+
+	err := json.Unmarshal([]byte(properties), &revision.Properties)
+	return revision, err
+}
+
+// Revisions returns the revisions of the entry with uid, oldest first.
+func (b *Blog) Revisions(uid string) ([]Revision, error) {
+	return b.revisions.List(uid)
+}
+
+// keptOnRestore are the properties that are not part of the content of an
+// entry, so are not changed by restoring an earlier revision. This includes
+// post-status and visibility along with the hx- properties made from them, so
+// that they still agree when the entry is next updated.
+var keptOnRestore = []string{
+	"uid",
+	"url",
+	"published",
+	"hx-client-id",
+	"hx-deleted",
+	"post-status",
+	"visibility",
+	"hx-draft",
+	"hx-unlisted",
+	"hx-private",
+	"hx-scheduled",
+	"hx-aliases",
+	"syndication",
+	"mp-syndicate-to",
+}
+
+// Restore replaces the properties of the entry at url with those it had at
+// version, as if it had been updated to them.
+func (b *Blog) Restore(url string, version int) error {
+	oldData, err := b.Entry(url)
+	if err != nil {
+		return err
+	}
+
+	id, ok := oldData["uid"][0].(string)
+	if !ok {
+		return errors.New("post to restore not found")
+	}
+
+	revision, err := b.revisions.Get(id, version)
+	if err != nil {
+		return err
+	}
+
+	// only the content is restored, so that whether the entry is shown, where
+	// it has been syndicated and the urls it has had stay as they are now
+	newData := revision.Properties
+	for _, key := range keptOnRestore {
+		delete(newData, key)
+		if value, ok := oldData[key]; ok {
+			newData[key] = value
+		}
+	}
+	newData["updated"] = []any{time.Now().UTC().Format(time.RFC3339)}
+
+	if err := b.entries.DeleteSubject(id); err != nil {
+		return err
+	}
+	if err := b.entries.SetProperties(id, newData); err != nil {
+		return err
+	}
+	if err := b.revisions.Save(id, newData); err != nil {
+		return err
+	}
+	if err := b.search.Index(newData); err != nil {
+		return err
+	}
+
+	b.announceUpdate(url, oldData, newData, nil)
+	b.rebuild(oldData, newData)
+
+	return nil
+}
+
+// ignoredChanges are the properties that are not shown when comparing
+// revisions, as they are either added when read or change every time.
+var ignoredChanges = []string{"author", "updated"}
+
+// isIgnoredChange reports whether changes to the property are left out of the
+// history of an entry. Along with ignoredChanges, the properties only used
+// internally are left out as the history is public.
+func isIgnoredChange(property string) bool {
+	return slices.Contains(ignoredChanges, property) ||
+		strings.HasPrefix(property, "hx-") ||
+		strings.HasPrefix(property, "mp-")
+}
+
+// revisionChanges lists the values of each property that were removed or added
+// going from the properties in old to those in new.
+func revisionChanges(old, new map[string][]any) []page.PropertyChange {
+	var properties []string
+	for _, data := range []map[string][]any{old, new} {
+		for property := range data {
+			if !slices.Contains(properties, property) && !isIgnoredChange(property) {
+				properties = append(properties, property)
+			}
+		}
+	}
+	slices.Sort(properties)
+
+	var changes []page.PropertyChange
+	for _, property := range properties {
+		change := page.PropertyChange{
+			Property: property,
+			Removed:  missingFrom(old[property], new[property]),
+			Added:    missingFrom(new[property], old[property]),
+		}
+
+		if len(change.Removed) > 0 || len(change.Added) > 0 {
+			changes = append(changes, change)
+		}
+	}
+
+	return changes
+}
+
+// missingFrom returns the values in xs that are not in ys.
+func missingFrom(xs, ys []any) []any {
+	var missing []any
+
+	for _, x := range xs {
+		if !slices.ContainsFunc(ys, func(y any) bool { return reflect.DeepEqual(x, y) }) {
+			missing = append(missing, x)
+		}
+	}
+
+	return missing
+}
+
+// history lists the changes made by each revision, newest first.
+func history(revisions []Revision) []page.Revision {
+	var (
+		list     []page.Revision
+		previous map[string][]any
+	)
+
+	for _, revision := range revisions {
+		list = append(list, page.Revision{
+			Version:   revision.Version,
+			CreatedAt: revision.CreatedAt,
+			Changes:   revisionChanges(previous, revision.Properties),
+		})
+
+		previous = revision.Properties
+	}
+
+	slices.Reverse(list)
+
+	return list
+}
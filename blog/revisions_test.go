@@ -0,0 +1,176 @@
+package blog
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/url"
+	"testing"
+
+	"hawx.me/code/assert"
+	"hawx.me/code/numbersix"
+	"hawx.me/code/tally-ho/internal/mfutil"
+	"hawx.me/code/tally-ho/internal/page"
+)
+
+func TestRevisionStore(t *testing.T) {
+	assert := assert.New(t)
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	assert.Nil(err)
+
+	store, err := newRevisionStore(db, "")
+	assert.Nil(err)
+
+	assert.Nil(store.Save("1", map[string][]any{"name": {"first"}}))
+	assert.Nil(store.Save("2", map[string][]any{"name": {"other"}}))
+	assert.Nil(store.Save("1", map[string][]any{"name": {"second"}}))
+
+	revisions, err := store.List("1")
+	assert.Nil(err)
+	if assert.Len(revisions, 2) {
+		assert.Equal(1, revisions[0].Version)
+		assert.Equal("first", revisions[0].Properties["name"][0])
+		assert.Equal(2, revisions[1].Version)
+		assert.Equal("second", revisions[1].Properties["name"][0])
+	}
+
+	revision, err := store.Get("2", 1)
+	assert.Nil(err)
+	assert.Equal("other", revision.Properties["name"][0])
+
+	_, err = store.Get("2", 2)
+	assert.True(errors.Is(err, ErrNotFound))
+}
+
+func TestRestore(t *testing.T) {
+	assert := assert.New(t)
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	assert.Nil(err)
+
+	entries, err := numbersix.For(db, "entries")
+	assert.Nil(err)
+
+	revisions, err := newRevisionStore(db, "")
+	assert.Nil(err)
+
+	search, err := newSearchIndex(db, "")
+	assert.Nil(err)
+
+	b := &Blog{local: true, entries: entries, revisions: revisions, search: search, hubPublisher: fakeHubPublisher{}}
+
+	first := map[string][]any{
+		"uid":             {"1"},
+		"url":             {"http://example.com/1"},
+		"name":            {"first"},
+		"hx-kind":         {"article"},
+		"hx-draft":        {true},
+		"hx-scheduled":    {true},
+		"mp-syndicate-to": {"https://flickr.com/"},
+	}
+	assert.Nil(entries.SetProperties("1", first))
+	assert.Nil(revisions.Save("1", first))
+
+	assert.Nil(entries.SetProperties("1", map[string][]any{
+		"name":        {"second"},
+		"syndication": {"https://flickr.com/photos/1"},
+		"hx-aliases":  {"http://example.com/old"},
+	}))
+	assert.Nil(entries.DeletePredicate("1", "hx-draft"))
+	assert.Nil(entries.DeletePredicate("1", "hx-scheduled"))
+	assert.Nil(entries.DeletePredicate("1", "mp-syndicate-to"))
+	assert.Nil(entries.DeleteValue("1", "name", "first"))
+	assert.Nil(entries.Set("1", "hx-deleted", true))
+
+	assert.Nil(b.Restore("http://example.com/1", 1))
+
+	entry, err := b.EntryByUID("1")
+	assert.Nil(err)
+	assert.Equal([]any{"first"}, entry["name"])
+	assert.Equal(true, entry["hx-deleted"][0])
+	assert.Equal([]any{"https://flickr.com/photos/1"}, entry["syndication"])
+	assert.Equal([]any{"http://example.com/old"}, entry["hx-aliases"])
+	assert.Nil(entry["hx-draft"])
+	assert.Nil(entry["hx-scheduled"])
+	assert.Nil(entry["mp-syndicate-to"])
+	assert.Len(entry["updated"], 1)
+
+	list, err := b.Revisions("1")
+	assert.Nil(err)
+	assert.Len(list, 2)
+}
+
+func TestRestoreThenUpdate(t *testing.T) {
+	assert := assert.New(t)
+
+	db, err := sql.Open("sqlite3", "file:restoreupdate?mode=memory&cache=shared")
+	assert.Nil(err)
+
+	baseURL, _ := url.Parse("http://localhost:8080/")
+	b, err := New(slog.Default(), Config{
+		Me:      "http://localhost:8080/",
+		BaseURL: baseURL,
+	}, page.Context{Name: "test"}.WithPath("/"), db, fakeHubPublisher{}, nil)
+	assert.Nil(err)
+	defer b.Close()
+
+	location, err := b.Create(map[string][]any{
+		"h":           {"entry"},
+		"content":     {"first"},
+		"post-status": {"draft"},
+		"visibility":  {"private"},
+	})
+	assert.Nil(err)
+
+	assert.Nil(b.Update(location, map[string][]any{
+		"content":     {"second"},
+		"post-status": {"published"},
+		"visibility":  {"public"},
+	}, nil, nil, nil))
+
+	assert.Nil(b.Restore(location, 1))
+
+	// a later update makes the flags again from post-status and visibility, so
+	// restoring must not bring back the old ones
+	assert.Nil(b.Update(location, map[string][]any{"category": {"later"}}, nil, nil, nil))
+
+	entry, err := b.Entry(location)
+	assert.Nil(err)
+	assert.Equal("first", mfutil.Get(entry, "content.text"))
+	assert.Equal([]any{"published"}, entry["post-status"])
+	assert.Equal([]any{"public"}, entry["visibility"])
+	assert.Nil(entry["hx-draft"])
+	assert.Nil(entry["hx-private"])
+	assert.Nil(entry["hx-unlisted"])
+}
+
+func TestHistory(t *testing.T) {
+	assert := assert.New(t)
+
+	revisions := []Revision{
+		{Version: 1, Properties: map[string][]any{
+			"name":     {"first"},
+			"category": {"a", "b"},
+		}},
+		{Version: 2, Properties: map[string][]any{
+			"name":            {"second"},
+			"category":        {"a"},
+			"updated":         {"2020-10-01T12:00:00Z"},
+			"hx-client-id":    {"https://client.example.com/"},
+			"hx-draft":        {true},
+			"mp-syndicate-to": {"https://flickr.com/"},
+		}},
+	}
+
+	assert.Equal([]page.Revision{
+		{Version: 2, Changes: []page.PropertyChange{
+			{Property: "category", Removed: []any{"b"}},
+			{Property: "name", Removed: []any{"first"}, Added: []any{"second"}},
+		}},
+		{Version: 1, Changes: []page.PropertyChange{
+			{Property: "category", Added: []any{"a", "b"}},
+			{Property: "name", Added: []any{"first"}},
+		}},
+	}, history(revisions))
+}
@@ -0,0 +1,85 @@
+package blog
+
+import (
+	"log/slog"
+	"time"
+
+	"hawx.me/code/numbersix"
+	"hawx.me/code/tally-ho/internal/mfutil"
+)
+
+// schedulePollInterval is how often entries are checked to see whether they
+// have reached their scheduled time.
+const schedulePollInterval = time.Minute
+
+// Schedule starts releasing entries as they reach their published time. This
+// should only be used when serving the blog, so that a command run against it
+// does not announce entries before it exits.
+func (b *Blog) Schedule() {
+	go b.schedule()
+}
+
+// schedule releases entries as they reach their published time until the Blog
+// is closed. As the check is made against the stored entries, any scheduled
+// before a restart will still be released.
+func (b *Blog) schedule() {
+	ticker := time.NewTicker(schedulePollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := b.releaseScheduled(time.Now()); err != nil {
+			slog.Error("release scheduled entries", slog.Any("err", err))
+		}
+
+		select {
+		case <-ticker.C:
+		case <-b.done:
+			return
+		}
+	}
+}
+
+// markScheduled holds back the entry if it is to be published after now.
+func markScheduled(data map[string][]any, now time.Time) {
+	s, _ := mfutil.Get(data, "published").(string)
+	if published, err := time.Parse(time.RFC3339, s); err == nil && published.After(now) {
+		data["hx-scheduled"] = []any{true}
+	}
+}
+
+// releaseScheduled publishes all entries that were scheduled for a time before
+// now, performing the tasks that would have happened on creation.
+func (b *Blog) releaseScheduled(now time.Time) error {
+	triples, err := b.entries.List(
+		numbersix.
+			Has("hx-scheduled").
+			Without("hx-deleted").
+			Without("hx-draft"),
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, group := range numbersix.Grouped(triples) {
+		s, _ := mfutil.Get(group.Properties, "published").(string)
+		published, err := time.Parse(time.RFC3339, s)
+		if err != nil || published.After(now) {
+			continue
+		}
+
+		if err := b.entries.DeletePredicate(group.Subject, "hx-scheduled"); err != nil {
+			return err
+		}
+
+		data := b.withAuthor(group.Properties)
+		delete(data, "hx-scheduled")
+		location, _ := mfutil.Get(data, "url").(string)
+
+		slog.Info("released scheduled entry", slog.String("uid", group.Subject), slog.String("url", location))
+
+		b.announce(location, data)
+		b.rebuild(data)
+	}
+
+	return nil
+}
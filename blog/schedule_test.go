@@ -0,0 +1,100 @@
+package blog
+
+import (
+	"database/sql"
+	"log/slog"
+	"net/url"
+	"testing"
+	"time"
+
+	"hawx.me/code/assert"
+	"hawx.me/code/numbersix"
+	"hawx.me/code/tally-ho/internal/page"
+)
+
+type fakeHubPublisher struct{}
+
+func (fakeHubPublisher) Publish(topic string) error { return nil }
+
+func TestReleaseScheduled(t *testing.T) {
+	assert := assert.New(t)
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	assert.Nil(err)
+
+	entries, err := numbersix.For(db, "entries")
+	assert.Nil(err)
+
+	b := &Blog{local: true, entries: entries, hubPublisher: fakeHubPublisher{}}
+
+	assert.Nil(entries.SetProperties("past", map[string][]any{
+		"uid":          {"past"},
+		"url":          {"http://example.com/past"},
+		"published":    {"2020-10-01T12:00:00Z"},
+		"hx-scheduled": {true},
+	}))
+	assert.Nil(entries.SetProperties("future", map[string][]any{
+		"uid":          {"future"},
+		"url":          {"http://example.com/future"},
+		"published":    {"2020-10-03T12:00:00Z"},
+		"hx-scheduled": {true},
+	}))
+
+	assert.Nil(b.releaseScheduled(time.Date(2020, time.October, 2, 12, 0, 0, 0, time.UTC)))
+
+	past, err := b.EntryByUID("past")
+	assert.Nil(err)
+	assert.False(isScheduled(past))
+
+	future, err := b.EntryByUID("future")
+	assert.Nil(err)
+	assert.True(isScheduled(future))
+}
+
+func TestUpdateSchedulesFuturePublished(t *testing.T) {
+	assert := assert.New(t)
+
+	db, err := sql.Open("sqlite3", "file:updateschedule?mode=memory&cache=shared")
+	assert.Nil(err)
+
+	baseURL, _ := url.Parse("http://localhost:8080/")
+	b, err := New(slog.Default(), Config{
+		Me:      "http://localhost:8080/",
+		BaseURL: baseURL,
+	}, page.Context{Name: "test"}.WithPath("/"), db, fakeHubPublisher{}, nil)
+	assert.Nil(err)
+	defer b.Close()
+
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+
+	draft, err := b.Create(map[string][]any{
+		"h":           {"entry"},
+		"content":     {"a draft"},
+		"post-status": {"draft"},
+	})
+	assert.Nil(err)
+
+	assert.Nil(b.Update(draft, map[string][]any{
+		"post-status": {"published"},
+		"published":   {future},
+	}, nil, nil, nil))
+
+	data, err := b.Entry(draft)
+	assert.Nil(err)
+	assert.False(isDraft(data))
+	assert.True(isScheduled(data))
+
+	published, err := b.Create(map[string][]any{
+		"h":       {"entry"},
+		"content": {"already out"},
+	})
+	assert.Nil(err)
+
+	assert.Nil(b.Update(published, map[string][]any{
+		"published": {future},
+	}, nil, nil, nil))
+
+	data, err = b.Entry(published)
+	assert.Nil(err)
+	assert.False(isScheduled(data))
+}
@@ -0,0 +1,212 @@
+package blog
+
+import (
+	"database/sql"
+	"strings"
+
+	"hawx.me/code/numbersix"
+	"hawx.me/code/tally-ho/internal/mfutil"
+)
+
+// searchLimit is the most entries a search will return.
+const searchLimit = 25
+
+type searchIndex struct {
+	db    *sql.DB
+	table string
+}
+
+func newSearchIndex(db *sql.DB, prefix string) (*searchIndex, error) {
+	s := &searchIndex{db: db, table: prefix + "search"}
+	return s, s.init()
+}
+
+func (s *searchIndex) init() error {
+	_, err := s.db.Exec(`CREATE VIRTUAL TABLE IF NOT EXISTS ` + s.table + ` USING fts4(
+    UID,
+    Published,
+    Name,
+    Content,
+    Category,
+    Cites,
+    notindexed=UID,
+    notindexed=Published,
+    tokenize=unicode61
+  );`)
+
+	return err
+}
+
+// Index adds the entry to the index, replacing any previous version of it.
+// Deleted entries are removed instead.
+func (s *searchIndex) Index(data map[string][]any) error {
+	uid, _ := mfutil.Get(data, "uid").(string)
+
+	if err := s.Remove(uid); err != nil {
+		return err
+	}
+
+	if len(data["hx-deleted"]) > 0 {
+		return nil
+	}
+
+	published, _ := mfutil.Get(data, "published").(string)
+	name, _ := mfutil.Get(data, "name").(string)
+	content, _ := mfutil.Get(data, "content.text", "content").(string)
+
+	_, err := s.db.Exec(`INSERT INTO `+s.table+`(UID, Published, Name, Content, Category, Cites)
+      VALUES (?, ?, ?, ?, ?, ?);`,
+		uid,
+		published,
+		name,
+		content,
+		strings.Join(searchStrings(data["category"]), " "),
+		strings.Join(citeNames(data), " "))
+
+	return err
+}
+
+// Remove takes the entry with uid out of the index.
+func (s *searchIndex) Remove(uid string) error {
+	_, err := s.db.Exec(`DELETE FROM `+s.table+` WHERE UID = ?;`, uid)
+	return err
+}
+
+// Clear removes every entry from the index.
+func (s *searchIndex) Clear() error {
+	_, err := s.db.Exec(`DELETE FROM ` + s.table + `;`)
+	return err
+}
+
+// Search returns the uids of entries matching query, newest first, skipping the
+// first offset. The query is split in to words that must all be found, so any
+// syntax is not interpreted.
+func (s *searchIndex) Search(query string, limit, offset int) ([]string, error) {
+	match := searchMatch(query)
+	if match == "" {
+		return nil, nil
+	}
+
+	rows, err := s.db.Query(`SELECT UID FROM `+s.table+` WHERE `+s.table+` MATCH ? ORDER BY Published DESC LIMIT ? OFFSET ?`,
+		match,
+		limit,
+		offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var uids []string
+	for rows.Next() {
+		var uid string
+		if err := rows.Scan(&uid); err != nil {
+			return nil, err
+		}
+
+		uids = append(uids, uid)
+	}
+
+	return uids, rows.Err()
+}
+
+// searchMatch quotes each word of query, so that it is treated as text to find.
+func searchMatch(query string) string {
+	var terms []string
+	for _, word := range strings.Fields(query) {
+		if word = strings.ReplaceAll(word, `"`, ""); word != "" {
+			terms = append(terms, `"`+word+`"`)
+		}
+	}
+
+	return strings.Join(terms, " ")
+}
+
+func searchStrings(values []any) []string {
+	var strs []string
+	for _, value := range values {
+		if s, ok := value.(string); ok {
+			strs = append(strs, s)
+		}
+	}
+
+	return strs
+}
+
+// citeNames returns the names of any h-cites the entry refers to, such as the
+// post it is a reply to.
+func citeNames(data map[string][]any) []string {
+	var names []string
+
+	for key, values := range data {
+		if key == "author" {
+			continue
+		}
+
+		for _, value := range values {
+			if _, ok := value.(map[string]any); !ok {
+				continue
+			}
+
+			if name, ok := mfutil.Get(value, "properties.name").(string); ok {
+				names = append(names, name)
+			}
+		}
+	}
+
+	return names
+}
+
+// Search returns the listed entries that match query, newest first.
+func (b *Blog) Search(query string) ([]numbersix.Group, error) {
+	var groups []numbersix.Group
+
+	// entries that are not shown are only filtered out after matching, so keep
+	// reading matches until there are enough of those that are
+	for offset := 0; len(groups) < searchLimit; offset += searchLimit {
+		uids, err := b.search.Search(query, searchLimit, offset)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, uid := range uids {
+			data, err := b.EntryByUID(uid)
+			if err != nil {
+				continue
+			}
+
+			if !isPublished(data) || isUnlisted(data) || len(data["hx-deleted"]) > 0 {
+				continue
+			}
+
+			if len(groups) < searchLimit {
+				groups = append(groups, numbersix.Group{Subject: uid, Properties: data})
+			}
+		}
+
+		if len(uids) < searchLimit {
+			break
+		}
+	}
+
+	return groups, nil
+}
+
+// RebuildSearch replaces the search index with one built from every entry.
+func (b *Blog) RebuildSearch() error {
+	if err := b.search.Clear(); err != nil {
+		return err
+	}
+
+	triples, err := b.entries.List(numbersix.Without("hx-deleted"))
+	if err != nil {
+		return err
+	}
+
+	for _, group := range numbersix.Grouped(triples) {
+		if err := b.search.Index(group.Properties); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
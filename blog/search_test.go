@@ -0,0 +1,126 @@
+package blog
+
+import (
+	"database/sql"
+	"strconv"
+	"testing"
+	"time"
+
+	"hawx.me/code/assert"
+	"hawx.me/code/numbersix"
+)
+
+func TestSearch(t *testing.T) {
+	assert := assert.New(t)
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	assert.Nil(err)
+
+	entries, err := numbersix.For(db, "entries")
+	assert.Nil(err)
+
+	search, err := newSearchIndex(db, "")
+	assert.Nil(err)
+
+	b := &Blog{entries: entries, search: search}
+
+	for _, data := range []map[string][]any{
+		{
+			"uid":       {"1"},
+			"published": {"2020-10-01T12:00:00Z"},
+			"name":      {"Some thoughts on gardening"},
+		},
+		{
+			"uid":       {"2"},
+			"published": {"2020-10-02T12:00:00Z"},
+			"content":   {map[string]any{"html": "<p>my garden</p>", "text": "my garden is growing"}},
+			"category":  {"plants"},
+		},
+		{
+			"uid":       {"3"},
+			"published": {"2020-10-03T12:00:00Z"},
+			"like-of": {map[string]any{
+				"type":       []any{"h-cite"},
+				"properties": map[string][]any{"name": {"A garden tour"}},
+			}},
+		},
+		{
+			"uid":         {"4"},
+			"published":   {"2020-10-04T12:00:00Z"},
+			"name":        {"A hidden garden"},
+			"hx-unlisted": {true},
+		},
+	} {
+		assert.Nil(entries.SetProperties(data["uid"][0].(string), data))
+		assert.Nil(search.Index(data))
+	}
+
+	uids := func(query string) []string {
+		groups, err := b.Search(query)
+		assert.Nil(err)
+
+		var uids []string
+		for _, group := range groups {
+			uids = append(uids, group.Subject)
+		}
+		return uids
+	}
+
+	assert.Equal([]string{"1"}, uids("gardening"))
+	assert.Equal([]string{"3", "2"}, uids("garden"))
+	assert.Equal([]string{"2"}, uids("plants"))
+	assert.Equal([]string{"3"}, uids("garden tour"))
+	assert.Len(uids(`"unbalanced`), 0)
+	assert.Len(uids(""), 0)
+
+	assert.Nil(search.Remove("2"))
+	assert.Equal([]string{"3"}, uids("garden"))
+
+	assert.Nil(b.RebuildSearch())
+	assert.Equal([]string{"3", "2"}, uids("garden"))
+}
+
+func TestSearchSkipsUnlisted(t *testing.T) {
+	assert := assert.New(t)
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	assert.Nil(err)
+
+	entries, err := numbersix.For(db, "entries")
+	assert.Nil(err)
+
+	search, err := newSearchIndex(db, "")
+	assert.Nil(err)
+
+	b := &Blog{entries: entries, search: search}
+
+	for i := range searchLimit * 2 {
+		uid := strconv.Itoa(i)
+		data := map[string][]any{
+			"uid":       {uid},
+			"published": {time.Date(2020, 10, 1, 12, 0, i, 0, time.UTC).Format(time.RFC3339)},
+			"name":      {"A garden"},
+			"hx-draft":  {true},
+		}
+		if i == 0 {
+			delete(data, "hx-draft")
+		}
+
+		assert.Nil(entries.SetProperties(uid, data))
+		assert.Nil(search.Index(data))
+	}
+
+	groups, err := b.Search("garden")
+	assert.Nil(err)
+	if assert.Len(groups, 1) {
+		assert.Equal("0", groups[0].Subject)
+	}
+}
+
+func TestSearchMatch(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(`"a" "b"`, searchMatch("a  b"))
+	assert.Equal(`"a" "OR" "b"`, searchMatch(`a OR "b"`))
+	assert.Equal("", searchMatch(` " `))
+}
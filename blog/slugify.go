@@ -3,8 +3,14 @@ package blog
 import (
 	"regexp"
 	"strings"
+
+	"hawx.me/code/tally-ho/internal/mfutil"
 )
 
+// maxSlugWords is the number of words of content used to create a slug, when
+// there is no name for the entry.
+const maxSlugWords = 6
+
 var nonWord = regexp.MustCompile("\\W+")
 
 func slugify(s string) string {
@@ -16,3 +22,33 @@ func slugify(s string) string {
 
 	return s
 }
+
+// slugFor decides the slug to use for an entry. A requested 'mp-slug' takes
+// priority, otherwise it is made from the name or start of the content. If
+// neither is usable the kind of entry is used.
+func slugFor(data map[string][]any, kind string) string {
+	if s, ok := mfutil.Get(data, "mp-slug").(string); ok {
+		if slug := slugify(s); slug != "" {
+			return slug
+		}
+	}
+
+	if s, ok := mfutil.Get(data, "name").(string); ok {
+		if slug := slugify(s); slug != "" {
+			return slug
+		}
+	}
+
+	if s, ok := mfutil.Get(data, "content.text", "content").(string); ok {
+		words := strings.Fields(s)
+		if len(words) > maxSlugWords {
+			words = words[:maxSlugWords]
+		}
+
+		if slug := slugify(strings.Join(words, " ")); slug != "" {
+			return slug
+		}
+	}
+
+	return kind
+}
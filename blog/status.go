@@ -0,0 +1,43 @@
+package blog
+
+import "net/http"
+
+// isScheduled returns true if the entry has a published time in the future and
+// so should not yet be shown.
+func isScheduled(data map[string][]any) bool {
+	return len(data["hx-scheduled"]) > 0
+}
+
+// isDraft returns true if the entry was given a 'post-status' of draft, so
+// should only be shown to the owner.
+func isDraft(data map[string][]any) bool {
+	return len(data["hx-draft"]) > 0
+}
+
+// isPublished returns true if the entry is neither scheduled or a draft.
+func isPublished(data map[string][]any) bool {
+	return !isScheduled(data) && !isDraft(data)
+}
+
+// isUnlisted returns true if the entry should be left out of lists and feeds.
+// This is the case for a 'visibility' of unlisted or private.
+func isUnlisted(data map[string][]any) bool {
+	return len(data["hx-unlisted"]) > 0
+}
+
+// isPrivate returns true if the entry was given a 'visibility' of private, so
+// should only be shown to the owner.
+func isPrivate(data map[string][]any) bool {
+	return len(data["hx-private"]) > 0
+}
+
+// isOwner returns true if the request has been authenticated as the owner of
+// the blog.
+func (b *Blog) isOwner(r *http.Request) bool {
+	return b.config.IsOwner != nil && b.config.IsOwner(r)
+}
+
+// canView returns true if the entry can be shown in response to the request.
+func (b *Blog) canView(r *http.Request, data map[string][]any) bool {
+	return (isPublished(data) && !isPrivate(data)) || b.isOwner(r)
+}
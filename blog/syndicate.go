@@ -2,6 +2,8 @@ package blog
 
 import (
 	"log/slog"
+	"net/url"
+	"strings"
 )
 
 type Syndicator interface {
@@ -11,21 +13,60 @@ type Syndicator interface {
 }
 
 func (b *Blog) syndicate(location string, data map[string][]any) {
-	if syndicateTos, ok := data["mp-syndicate-to"]; ok && len(syndicateTos) > 0 {
-		for _, syndicateTo := range syndicateTos {
-			if syndicator, ok := b.syndicators[syndicateTo.(string)]; ok {
-				syndicatedLocation, err := syndicator.Create(data)
-				if err != nil {
-					slog.Error("create syndication", slog.String("to", syndicator.Name()), slog.Any("uid", data["uid"][0]), slog.Any("err", err))
-					continue
-				}
-
-				if err := b.Update(location, empty, map[string][]any{
-					"syndication": {syndicatedLocation},
-				}, empty, []string{}); err != nil {
-					slog.Error("confirming syndication", slog.String("to", syndicator.Name()), slog.Any("uid", data["uid"][0]), slog.Any("err", err))
-				}
-			}
+	b.syndicateTo(location, data, data["mp-syndicate-to"])
+}
+
+// syndicateTo creates the entry using each of the Syndicators with a UID listed
+// in targets, adding the resulting locations to its syndication. A target is
+// skipped if the entry has already been syndicated there.
+func (b *Blog) syndicateTo(location string, data map[string][]any, targets []any) {
+	for _, syndicateTo := range targets {
+		uid, _ := syndicateTo.(string)
+
+		syndicator, ok := b.syndicators[uid]
+		if !ok {
+			continue
+		}
+
+		if isSyndicatedTo(syndicator, data["syndication"]) {
+			slog.Info("already syndicated", slog.String("to", syndicator.Name()), slog.Any("uid", data["uid"][0]))
+			continue
+		}
+
+		syndicatedLocation, err := syndicator.Create(data)
+		if err != nil {
+			slog.Error("create syndication", slog.String("to", syndicator.Name()), slog.Any("uid", data["uid"][0]), slog.Any("err", err))
+			continue
+		}
+
+		if err := b.Update(location, map[string][]any{}, map[string][]any{
+			"syndication": {syndicatedLocation},
+		}, map[string][]any{}, []string{}); err != nil {
+			slog.Error("confirming syndication", slog.String("to", syndicator.Name()), slog.Any("uid", data["uid"][0]), slog.Any("err", err))
 		}
 	}
 }
+
+// isSyndicatedTo checks whether any of the syndication urls are on the same
+// site as the syndicator, which is identified by the host of its UID.
+func isSyndicatedTo(syndicator Syndicator, syndication []any) bool {
+	uid, err := url.Parse(syndicator.UID())
+	if err != nil || uid.Host == "" {
+		return false
+	}
+
+	for _, value := range syndication {
+		s, _ := value.(string)
+
+		u, err := url.Parse(s)
+		if err != nil {
+			continue
+		}
+
+		if strings.TrimPrefix(u.Hostname(), "www.") == strings.TrimPrefix(uid.Hostname(), "www.") {
+			return true
+		}
+	}
+
+	return false
+}
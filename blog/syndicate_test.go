@@ -0,0 +1,71 @@
+package blog
+
+import (
+	"database/sql"
+	"testing"
+
+	"hawx.me/code/assert"
+	"hawx.me/code/numbersix"
+)
+
+type fakeSyndicator struct {
+	uid     string
+	created []map[string][]any
+}
+
+func (s *fakeSyndicator) Create(data map[string][]any) (string, error) {
+	s.created = append(s.created, data)
+	return s.uid + "post/1", nil
+}
+
+func (s *fakeSyndicator) UID() string  { return s.uid }
+func (s *fakeSyndicator) Name() string { return "fake" }
+
+func TestSyndicateTo(t *testing.T) {
+	assert := assert.New(t)
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	assert.Nil(err)
+
+	entries, err := numbersix.For(db, "entries")
+	assert.Nil(err)
+
+	revisions, err := newRevisionStore(db, "")
+	assert.Nil(err)
+
+	search, err := newSearchIndex(db, "")
+	assert.Nil(err)
+
+	flickr := &fakeSyndicator{uid: "https://flickr.com/"}
+	github := &fakeSyndicator{uid: "https://github.com/"}
+
+	b := &Blog{
+		local:        true,
+		entries:      entries,
+		revisions:    revisions,
+		search:       search,
+		hubPublisher: fakeHubPublisher{},
+		syndicators: map[string]Syndicator{
+			flickr.uid: flickr,
+			github.uid: github,
+		},
+	}
+
+	data := map[string][]any{
+		"uid":         {"1"},
+		"url":         {"http://example.com/1"},
+		"published":   {"2020-10-01T12:00:00Z"},
+		"content":     {"hello"},
+		"syndication": {"https://www.flickr.com/photos/someone/1"},
+	}
+	assert.Nil(entries.SetProperties("1", data))
+
+	b.syndicateTo("http://example.com/1", data, []any{"https://flickr.com/", "https://github.com/", "https://what.com/"})
+
+	assert.Len(flickr.created, 0)
+	assert.Len(github.created, 1)
+
+	entry, err := b.Entry("http://example.com/1")
+	assert.Nil(err)
+	assert.Equal([]any{"https://www.flickr.com/photos/someone/1", "https://github.com/post/1"}, entry["syndication"])
+}
@@ -0,0 +1,194 @@
+package blog
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"hawx.me/code/tally-ho/internal/page"
+)
+
+// A Tombstone is what remains of an entry that has been purged, so that its url
+// continues to respond as gone.
+type Tombstone struct {
+	UID     string    `json:"uid"`
+	URL     string    `json:"url"`
+	Deleted time.Time `json:"deleted"`
+}
+
+type tombstoneStore struct {
+	db    *sql.DB
+	table string
+}
+
+func newTombstoneStore(db *sql.DB, prefix string) (*tombstoneStore, error) {
+	s := &tombstoneStore{db: db, table: prefix + "tombstones"}
+	return s, s.init()
+}
+
+func (s *tombstoneStore) init() error {
+	_, err := s.db.Exec(`CREATE TABLE IF NOT EXISTS ` + s.table + ` (
+    UID     TEXT PRIMARY KEY,
+    URL     TEXT UNIQUE,
+    Deleted DATETIME
+  );`)
+
+	return err
+}
+
+// Save records the tombstone, replacing any for the same entry.
+func (s *tombstoneStore) Save(tombstone Tombstone) error {
+	_, err := s.db.Exec(`INSERT OR REPLACE INTO `+s.table+`(UID, URL, Deleted) VALUES (?, ?, ?);`,
+		tombstone.UID,
+		tombstone.URL,
+		tombstone.Deleted)
+
+	return err
+}
+
+// ForUID returns the tombstone of the entry with uid.
+func (s *tombstoneStore) ForUID(uid string) (Tombstone, error) {
+	return s.get(`UID = ?`, uid)
+}
+
+// ForURL returns the tombstone of the entry at url.
+func (s *tombstoneStore) ForURL(url string) (Tombstone, error) {
+	return s.get(`URL = ?`, url)
+}
+
+// List returns every tombstone.
+func (s *tombstoneStore) List() ([]Tombstone, error) {
+	rows, err := s.db.Query(`SELECT UID, URL, Deleted FROM ` + s.table + ` ORDER BY UID`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tombstones []Tombstone
+	for rows.Next() {
+		var tombstone Tombstone
+		if err := rows.Scan(&tombstone.UID, &tombstone.URL, &tombstone.Deleted); err != nil {
+			return nil, err
+		}
+
+		tombstones = append(tombstones, tombstone)
+	}
+
+	return tombstones, rows.Err()
+}
+
+func (s *tombstoneStore) get(where, value string) (Tombstone, error) {
+	var tombstone Tombstone
+	err := s.db.QueryRow(`SELECT UID, URL, Deleted FROM `+s.table+` WHERE `+where, value).
+		Scan(&tombstone.UID, &tombstone.URL, &tombstone.Deleted)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return tombstone, fmt.Errorf("no tombstone for %s: %w", value, ErrNotFound)
+	}
+
+	return tombstone, err
+}
+
+// Purge permanently removes the entry at url, along with its revisions, the
+// mentions it has received and any media uploaded for it. Before anything is
+// removed webmentions are sent to the urls it mentions, which will find that it
+// is gone, so that they can remove their copies of it. A tombstone is kept so
+// that the url continues to respond as gone.
+func (b *Blog) Purge(url string) error {
+	data, err := b.Entry(url)
+	if err != nil {
+		return err
+	}
+
+	id, ok := data["uid"][0].(string)
+	if !ok {
+		return errors.New("post to purge not found")
+	}
+
+	if err := b.tombstones.Save(Tombstone{UID: id, URL: url, Deleted: deletedAt(data)}); err != nil {
+		return err
+	}
+
+	// the entry must already be gone for receivers of the webmentions to see it
+	if len(data["hx-deleted"]) == 0 {
+		if err := b.entries.Set(id, "hx-deleted", time.Now().UTC().Format(time.RFC3339)); err != nil {
+			return err
+		}
+	}
+
+	if isPublished(data) && !isPrivate(data) {
+		b.sendTo(url, findMentionedLinks(data))
+
+		if !isUnlisted(data) {
+			go b.hubPublish(data)
+		}
+	}
+
+	mentions, err := b.MentionsForEntry(url)
+	if err != nil {
+		return err
+	}
+	for _, mention := range mentions {
+		if err := b.mentions.DeleteSubject(mention.Subject); err != nil {
+			return err
+		}
+	}
+
+	fw := &FileWriter{MediaDir: b.config.MediaDir, MediaURL: b.config.MediaURL}
+	for _, key := range []string{"photo", "video", "audio"} {
+		for _, value := range data[key] {
+			location := mediaLocation(value)
+			if err := fw.RemoveFile(location); err != nil {
+				slog.Error("purge media", slog.String("url", location), slog.Any("err", err))
+			}
+		}
+	}
+
+	if err := b.search.Remove(id); err != nil {
+		return err
+	}
+	if err := b.revisions.Remove(id); err != nil {
+		return err
+	}
+	if err := b.entries.DeleteSubject(id); err != nil {
+		return err
+	}
+
+	b.rebuild(data)
+	return nil
+}
+
+// deletedAt returns the time the entry was deleted, or now if it has not been.
+// Entries deleted before the time was recorded will return the zero time.
+func deletedAt(data map[string][]any) time.Time {
+	deleted, ok := data["hx-deleted"]
+	if !ok || len(deleted) == 0 {
+		return time.Now().UTC()
+	}
+
+	if s, ok := deleted[0].(string); ok {
+		if t, err := time.Parse(time.RFC3339, s); err == nil {
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+// renderTombstone responds that the entry at url is gone.
+func (b *Blog) renderTombstone(w http.ResponseWriter, url string, deleted time.Time) error {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusGone)
+
+	if _, err := page.Tombstone(b.pageCtx, page.TombstoneData{
+		URL:     url,
+		Deleted: deleted,
+	}).WriteTo(w); err != nil {
+		return fmt.Errorf("render: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package blog
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"hawx.me/code/assert"
+	"hawx.me/code/tally-ho/internal/page"
+)
+
+func TestPurge(t *testing.T) {
+	assert := assert.New(t)
+
+	db, err := sql.Open("sqlite3", "file:purge?mode=memory&cache=shared")
+	assert.Nil(err)
+
+	mediaDir := t.TempDir()
+	assert.Nil(os.WriteFile(filepath.Join(mediaDir, "photo.jpg"), []byte("a photo"), 0o644))
+	assert.Nil(os.WriteFile(filepath.Join(mediaDir, "other.jpg"), []byte("another photo"), 0o644))
+
+	baseURL, _ := url.Parse("http://localhost:8080/")
+	mediaURL, _ := url.Parse("http://localhost:8081/")
+	b, err := New(slog.Default(), Config{
+		Me:       "http://localhost:8080/",
+		BaseURL:  baseURL,
+		MediaURL: mediaURL,
+		MediaDir: mediaDir,
+	}, page.Context{Name: "test"}.WithPath("/"), db, fakeHubPublisher{}, nil)
+	assert.Nil(err)
+	defer b.Close()
+
+	location, err := b.Create(map[string][]any{
+		"h":         {"entry"},
+		"content":   {"a photo"},
+		"photo":     {"http://localhost:8081/photo.jpg"},
+		"published": {"2020-10-01T12:00:00Z"},
+	})
+	assert.Nil(err)
+
+	entry, err := b.Entry(location)
+	assert.Nil(err)
+	uid := entry["uid"][0].(string)
+
+	assert.Nil(b.Mention("http://example.com/reply", map[string][]any{
+		"hx-target": {location},
+		"content":   {"nice"},
+	}))
+
+	assert.Nil(b.Delete(location))
+
+	handler := b.Handler()
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", strings.TrimPrefix(location, "http://localhost:8080"), nil))
+	assert.Equal(http.StatusGone, w.Code)
+	assert.True(strings.Contains(w.Body.String(), `class="dt-deleted"`))
+
+	assert.Nil(b.Purge(location))
+
+	_, err = b.Entry(location)
+	assert.True(errors.Is(err, ErrNotFound))
+
+	mentions, err := b.MentionsForEntry(location)
+	assert.Nil(err)
+	assert.Len(mentions, 0)
+
+	revisions, err := b.Revisions(uid)
+	assert.Nil(err)
+	assert.Len(revisions, 0)
+
+	_, err = os.Stat(filepath.Join(mediaDir, "photo.jpg"))
+	assert.True(os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(mediaDir, "other.jpg"))
+	assert.Nil(err)
+
+	for _, path := range []string{strings.TrimPrefix(location, "http://localhost:8080"), "/entry/" + uid} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+
+		assert.Equal(http.StatusGone, w.Code)
+		assert.True(strings.Contains(w.Body.String(), `href="`+location+`"`))
+		assert.True(strings.Contains(w.Body.String(), `class="dt-deleted"`))
+	}
+}
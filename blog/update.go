@@ -2,6 +2,7 @@ package blog
 
 import (
 	"errors"
+	"slices"
 	"time"
 )
 
@@ -48,6 +49,12 @@ func (b *Blog) Update(
 
 	b.massage(newData)
 
+	// an entry that has not been announced yet waits for a published time that
+	// has been moved into the future, but one that has can not be taken back
+	if !isPublished(oldData) {
+		markScheduled(newData, time.Now())
+	}
+
 	if err := b.entries.DeleteSubject(id); err != nil {
 		return err
 	}
@@ -55,8 +62,42 @@ func (b *Blog) Update(
 		return err
 	}
 
-	go b.sendUpdateWebmentions(url, oldData, newData)
-	go b.hubPublish()
+	if err := b.revisions.Save(id, newData); err != nil {
+		return err
+	}
+	if err := b.search.Index(newData); err != nil {
+		return err
+	}
+
+	b.announceUpdate(url, oldData, newData, slices.Concat(replace["mp-syndicate-to"], add["mp-syndicate-to"]))
+	b.rebuild(oldData, newData)
 
 	return nil
 }
+
+// announceUpdate performs the tasks for an entry that has changed from oldData
+// to newData, syndicating it to any new targets.
+func (b *Blog) announceUpdate(url string, oldData, newData map[string][]any, targets []any) {
+	if !isPublished(newData) {
+		return
+	}
+
+	// a draft being published is treated as if it had just been created
+	if isDraft(oldData) {
+		b.announce(url, newData)
+		return
+	}
+
+	if !isPrivate(newData) {
+		go b.sendUpdateWebmentions(url, oldData, newData)
+
+		if len(targets) > 0 {
+			go b.syndicateTo(url, newData, targets)
+		}
+	}
+
+	// the feeds only need to change if the entry is, or was, listed
+	if !isUnlisted(newData) || !isUnlisted(oldData) {
+		go b.hubPublish(oldData, newData)
+	}
+}
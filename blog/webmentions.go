@@ -17,16 +17,7 @@ func (b *Blog) sendWebmentions(location string, data map[string][]interface{}) {
 	// ensure that the entry exists
 	time.Sleep(time.Second)
 
-	links := findMentionedLinks(data)
-	slog.Info("sending webmentions", slog.Any("links", links))
-
-	if !b.local {
-		for _, link := range links {
-			if err := webmention.Send(location, link); err != nil {
-				slog.Error("send webmention", slog.String("source", location), slog.String("target", link), slog.Any("err", err))
-			}
-		}
-	}
+	b.sendTo(location, findMentionedLinks(data))
 }
 
 func (b *Blog) sendUpdateWebmentions(location string, oldData, newData map[string][]interface{}) {
@@ -38,6 +29,12 @@ func (b *Blog) sendUpdateWebmentions(location string, oldData, newData map[strin
 		}
 	}
 
+	b.sendTo(location, links)
+}
+
+// sendTo sends a webmention from location to each of the links, unless running
+// locally.
+func (b *Blog) sendTo(location string, links []string) {
 	slog.Info("sending webmentions", slog.Any("links", links))
 
 	if !b.local {
@@ -4,19 +4,33 @@ import (
 	"log/slog"
 	"net/url"
 	"time"
+
+	"hawx.me/code/tally-ho/internal/mfutil"
 )
 
 type HubPublisher interface {
 	Publish(topic string) error
 }
 
-func (b *Blog) hubPublish() {
-	// for now just publish the main things that will always change
-	changed := []string{
-		b.pageCtx.Path(""),
-		b.pageCtx.Path("feed/atom"),
-		b.pageCtx.Path("feed/jsonfeed"),
-		b.pageCtx.Path("feed/rss"),
+// hubPublish lets the hub know that the main list and feeds have changed, along
+// with the feeds for the kind and categories of each version of the entry.
+func (b *Blog) hubPublish(datas ...map[string][]any) {
+	changed := []string{b.pageCtx.Path("")}
+
+	lists := []string{""}
+	for _, data := range datas {
+		if kind, ok := mfutil.Get(data, "hx-kind").(string); ok {
+			lists = append(lists, "kind/"+kind+"/")
+		}
+		for _, category := range searchStrings(data["category"]) {
+			lists = append(lists, "category/"+url.PathEscape(category)+"/")
+		}
+	}
+
+	for _, list := range uniquePaths(lists) {
+		for _, format := range feedFormats {
+			changed = append(changed, b.pageCtx.Path(list+"feed/"+format))
+		}
 	}
 
 	// ensure that the entry exists
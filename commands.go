@@ -0,0 +1,207 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"hawx.me/code/tally-ho/blog"
+)
+
+// runCommand performs a task, given by the first arg, instead of serving the
+// sites.
+func runCommand(args []string, sites []*site) error {
+	fs := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	fs.Usage = usage
+	siteURL := fs.String("site", "", "")
+
+	switch args[0] {
+	case "rebuild-search":
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		s, err := chooseSite(sites, *siteURL)
+		if err != nil {
+			return err
+		}
+
+		return s.blog.RebuildSearch()
+
+	case "build":
+		out := fs.String("out", "", "")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *out == "" {
+			return errors.New("build requires --out")
+		}
+
+		s, err := chooseSite(sites, *siteURL)
+		if err != nil {
+			return err
+		}
+
+		return s.blog.Build(*out)
+
+	case "export":
+		format := fs.String("format", blog.FormatMF2, "")
+		out := fs.String("out", "", "")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		s, err := chooseSite(sites, *siteURL)
+		if err != nil {
+			return err
+		}
+
+		return exportSite(s, *format, *out)
+
+	case "import":
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if fs.NArg() != 1 {
+			return errors.New("import requires the path of an export")
+		}
+
+		s, err := chooseSite(sites, *siteURL)
+		if err != nil {
+			return err
+		}
+
+		return importSite(s, fs.Arg(0))
+
+	case "purge":
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if fs.NArg() != 1 {
+			return errors.New("purge requires the url of an entry")
+		}
+
+		s, err := chooseSite(sites, *siteURL)
+		if err != nil {
+			return err
+		}
+
+		return s.blog.Purge(fs.Arg(0))
+
+	case "redirect":
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		s, err := chooseSite(sites, *siteURL)
+		if err != nil {
+			return err
+		}
+
+		return redirectSite(s, fs.Args())
+
+	default:
+		usage()
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+// chooseSite returns the site with the baseURL, or the only site if none is
+// given.
+func chooseSite(sites []*site, baseURL string) (*site, error) {
+	if baseURL == "" {
+		if len(sites) > 1 {
+			return nil, errors.New("--site must be given when more than one site is configured")
+		}
+
+		return sites[0], nil
+	}
+
+	for _, s := range sites {
+		if s.conf.BaseURL == baseURL {
+			return s, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no site configured with baseURL %q", baseURL)
+}
+
+func exportSite(s *site, format, out string) error {
+	archive, err := s.blog.Export()
+	if err != nil {
+		return err
+	}
+
+	if s.fw.MediaDir != "" {
+		if archive.Media, err = s.fw.Manifest(); err != nil {
+			return fmt.Errorf("media manifest: %w", err)
+		}
+	}
+
+	var w io.Writer = os.Stdout
+	if out != "" {
+		file, err := os.Create(out)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		w = file
+	}
+
+	return blog.WriteArchive(w, archive, format)
+}
+
+func importSite(s *site, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	archive, err := blog.ReadArchive(file)
+	if err != nil {
+		return fmt.Errorf("read archive: %w", err)
+	}
+
+	return s.blog.Import(archive)
+}
+
+func redirectSite(s *site, args []string) error {
+	if len(args) == 0 {
+		return errors.New("redirect requires one of add, remove or list")
+	}
+
+	switch args[0] {
+	case "add":
+		if len(args) != 3 {
+			return errors.New("redirect add requires the url to redirect from and to")
+		}
+
+		return s.blog.AddRedirect(args[1], args[2])
+
+	case "remove":
+		if len(args) != 2 {
+			return errors.New("redirect remove requires the url to stop redirecting")
+		}
+
+		return s.blog.RemoveRedirect(args[1])
+
+	case "list":
+		redirects, err := s.blog.Redirects()
+		if err != nil {
+			return err
+		}
+
+		for _, redirect := range redirects {
+			fmt.Println(redirect.From, redirect.To)
+		}
+
+		return nil
+
+	default:
+		return fmt.Errorf("unknown redirect command %q", args[0])
+	}
+}
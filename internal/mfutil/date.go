@@ -0,0 +1,42 @@
+package mfutil
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+var dateLayouts = []string{
+	time.RFC3339,
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05-0700",
+	"2006-01-02T15:04-0700",
+	"2006-01-02 15:04:05-0700",
+	"2006-01-02 15:04-0700",
+	"Mon, _2 Jan 2006 15:04:05 MST",
+	"Mon, _2 Jan 2006 15:04:05 -0700",
+	time.ANSIC,
+	time.UnixDate,
+	time.RubyDate,
+	time.RFC822,
+	time.RFC822Z,
+	time.RFC850,
+	time.RFC1123,
+	time.RFC1123Z,
+	"Mon, 2, Jan 2006 15:4",
+	"02 Jan 2006 15:04:05 MST",
+}
+
+// ParseDate reads a date given as a property value, such as published, trying
+// the formats that clients have been seen to send.
+func ParseDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, errors.New("could not parse date: " + s)
+}
@@ -2,6 +2,7 @@ package mfutil
 
 import (
 	"testing"
+	"time"
 
 	"hawx.me/code/assert"
 )
@@ -93,3 +94,23 @@ func TestSafeGetAll(t *testing.T) {
 	assert.True(ok)
 	assert.Equal([]interface{}{"a nested"}, value)
 }
+
+func TestParseDate(t *testing.T) {
+	expected := time.Date(2020, time.March, 4, 10, 11, 0, 0, time.UTC)
+
+	for _, s := range []string{
+		"2020-03-04T10:11:00Z",
+		"2020-03-04T10:11:00+0000",
+		"2020-03-04 10:11+0000",
+		" Wed, 04 Mar 2020 10:11:00 +0000 ",
+	} {
+		t.Run(s, func(t *testing.T) {
+			parsed, err := ParseDate(s)
+			assert.Nil(t, err)
+			assert.True(t, expected.Equal(parsed))
+		})
+	}
+
+	_, err := ParseDate("yesterday")
+	assert.NotNil(t, err)
+}
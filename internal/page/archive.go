@@ -0,0 +1,177 @@
+package page
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"hawx.me/code/lmth"
+	. "hawx.me/code/lmth/elements"
+)
+
+type ArchiveData struct {
+	// Period is the year, month or day shown, as 2006, 2006-01 or 2006-01-02
+	Period string
+	// Counts are the number of entries published in each year, month and day
+	// that has any, keyed in the same way as Period
+	Counts       map[string]int
+	GroupedPosts []GroupedPosts
+}
+
+func Archive(ctx Context, data ArchiveData) lmth.Node {
+	title := archiveTitle(data.Period)
+
+	return Html(lmth.Attr{"lang": "en"},
+		postsHead(ctx, "archive for "+title),
+		Body(lmth.Attr{},
+			nav(ctx),
+			buttons(Span(lmth.Attr{"class": "page"},
+				lmth.Text("archive for "),
+				Strong(lmth.Attr{}, lmth.Text(title)),
+			)),
+			Main(lmth.Attr{},
+				calendar(ctx, data.Period, data.Counts),
+				lmth.Toggle(len(data.GroupedPosts) == 0,
+					P(lmth.Attr{}, lmth.Text("Nothing was posted in "+title+".")),
+				),
+				lmth.Map(entryGrouping, data.GroupedPosts),
+			),
+		),
+		pageFooter(ctx),
+	)
+}
+
+func archiveTitle(period string) string {
+	if t, err := time.Parse(time.DateOnly, period); err == nil {
+		return t.Format("January 02, 2006")
+	}
+	if t, err := time.Parse("2006-01", period); err == nil {
+		return t.Format("January 2006")
+	}
+
+	return period
+}
+
+// archivePath returns the path of the archive page for a period, given as 2006,
+// 2006-01 or 2006-01-02.
+func archivePath(ctx Context, period string) string {
+	return ctx.Path(strings.ReplaceAll(period, "-", "/"))
+}
+
+// calendar shows the years that have entries, then the months of the year
+// being shown, then the days of the month being shown.
+func calendar(ctx Context, period string, counts map[string]int) lmth.Node {
+	var years []string
+	for key := range counts {
+		if len(key) == 4 {
+			years = append(years, key)
+		}
+	}
+	slices.Sort(years)
+
+	year, month := period, ""
+	if len(period) >= 7 {
+		year, month = period[:4], period[:7]
+	}
+
+	return Nav(lmth.Attr{"class": "calendar"},
+		Ol(lmth.Attr{"class": "years"},
+			lmth.Map(func(y string) lmth.Node {
+				return Li(lmth.Attr{},
+					calendarLink(ctx, y, y, y == year, counts[y]),
+				)
+			}, years),
+		),
+		lmth.Toggle(len(year) == 4,
+			Ol(lmth.Attr{"class": "months"},
+				lmth.Map(func(m int) lmth.Node {
+					key := fmt.Sprintf("%s-%02d", year, m)
+
+					return Li(lmth.Attr{},
+						calendarLink(ctx, key, time.Month(m).String()[:3], key == month, counts[key]),
+					)
+				}, []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}),
+			),
+		),
+		lmth.Toggle(month != "",
+			calendarMonth(ctx, month, period, counts),
+		),
+	)
+}
+
+// calendarMonth shows the days of the month as a table, with a row for each
+// week starting on Monday.
+func calendarMonth(ctx Context, month, period string, counts map[string]int) lmth.Node {
+	first, err := time.Parse("2006-01", month)
+	if err != nil {
+		return lmth.Text("")
+	}
+
+	var weeks [][]int
+	week := make([]int, (int(first.Weekday())+6)%7)
+	for d := first; d.Month() == first.Month(); d = d.AddDate(0, 0, 1) {
+		week = append(week, d.Day())
+
+		if len(week) == 7 {
+			weeks = append(weeks, week)
+			week = nil
+		}
+	}
+	if len(week) > 0 {
+		weeks = append(weeks, week)
+	}
+
+	return Table(lmth.Attr{"class": "days"},
+		Thead(lmth.Attr{},
+			Tr(lmth.Attr{},
+				lmth.Map(func(name string) lmth.Node {
+					return Th(lmth.Attr{"scope": "col"}, lmth.Text(name))
+				}, []string{"Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"}),
+			),
+		),
+		Tbody(lmth.Attr{},
+			lmth.Map(func(week []int) lmth.Node {
+				return Tr(lmth.Attr{},
+					lmth.Map(func(day int) lmth.Node {
+						if day == 0 {
+							return Td(lmth.Attr{})
+						}
+
+						key := fmt.Sprintf("%s-%02d", month, day)
+						return Td(lmth.Attr{},
+							calendarLink(ctx, key, strconv.Itoa(day), key == period, counts[key]),
+						)
+					}, week),
+				)
+			}, weeks),
+		),
+	)
+}
+
+// calendarLink links to the archive for the period, if anything was posted in
+// it.
+func calendarLink(ctx Context, period, text string, current bool, count int) lmth.Node {
+	if count == 0 {
+		return Span(lmth.Attr{}, lmth.Text(text))
+	}
+
+	title := fmt.Sprintf("%d posts", count)
+	if count == 1 {
+		title = "1 post"
+	}
+
+	attr := lmth.Attr{
+		"href":  archivePath(ctx, period),
+		"title": title,
+	}
+	if current {
+		attr["aria-current"] = "page"
+	}
+
+	return A(attr,
+		lmth.Text(text),
+		Small(lmth.Attr{"class": "count"}, lmth.Text(" "+strconv.Itoa(count))),
+	)
+}
@@ -28,7 +28,7 @@ func Day(ctx Context, data DayData) lmth.Node {
 			buttons(buttonsLikesFor(formattedTime)),
 			Main(lmth.Attr{},
 				lmth.Map(func(group numbersix.Group) lmth.Node {
-					return Article(lmth.Attr{"class": "h-entry " + templateGet(group.Properties, "hx-kind")},
+					return Article(lmth.Attr{"class": entryClass(group.Properties)},
 						entry(group.Properties),
 						entryMeta(group.Properties),
 					)
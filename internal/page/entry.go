@@ -9,7 +9,10 @@ import (
 func entry(meta map[string][]any) lmth.Node {
 	var nodes []lmth.Node
 
-	if mfutil.Has(meta, "rsvp") {
+	t := postType(meta)
+
+	switch t.Type {
+	case "rsvp":
 		nodes = append(nodes, H2(lmth.Attr{"class": "p-summary"},
 			Data(lmth.Attr{"class": "p-rsvp", "value": templateGet(meta, "rsvp")},
 				lmth.Text(formatHumanRSVP(templateGet(meta, "rsvp"))),
@@ -19,15 +22,9 @@ func entry(meta map[string][]any) lmth.Node {
 				lmth.Text(templateGetOr(meta, "name", "an event")),
 			),
 		))
-	} else if mfutil.Has(meta, "like-of") {
-		nodes = append(nodes, entryH2(meta, "liked", "like-of"))
-	} else if mfutil.Has(meta, "bookmark-of") {
-		nodes = append(nodes, entryH2(meta, "bookmarked", "bookmark-of"))
-	} else if mfutil.Has(meta, "in-reply-to") {
-		nodes = append(nodes, entryH2(meta, "replied to", "in-reply-to"))
-	} else if mfutil.Has(meta, "repost-of") {
-		nodes = append(nodes, entryH2(meta, "reposted", "repost-of"))
-	} else if mfutil.Has(meta, "read-of") {
+	case "review":
+		nodes = append(nodes, reviewSummary(meta))
+	case "read":
 		citeNodes := []lmth.Node{
 			Strong(lmth.Attr{"class": "p-name"},
 				lmth.Text(templateGet(meta, "read-of.properties.name")),
@@ -49,24 +46,17 @@ func entry(meta map[string][]any) lmth.Node {
 				citeNodes...,
 			),
 		))
-	} else if mfutil.Has(meta, "drank") {
-		nodes = append(nodes, H2(lmth.Attr{"class": "p-summary"},
-			lmth.Text("drank "),
-			Strong(lmth.Attr{},
-				lmth.Text(templateGet(meta, "drank.properties.name")),
-			),
-		))
-	} else if mfutil.Has(meta, "ate") {
+	case "drank", "ate":
 		nodes = append(nodes, H2(lmth.Attr{"class": "p-summary"},
-			lmth.Text("ate "),
+			lmth.Text(t.Verb+" "),
 			Strong(lmth.Attr{},
-				lmth.Text(templateGet(meta, "ate.properties.name")),
+				lmth.Text(templateGet(meta, t.Of+".properties.name")),
 			),
 		))
-	} else if mfutil.Has(meta, "checkin") {
+	case "checkin":
 		nodes = append(nodes, P(lmth.Attr{"class": "h-card p-summary"},
 			H2(lmth.Attr{},
-				lmth.Text("checked in to "),
+				lmth.Text(t.Verb+" "),
 				A(lmth.Attr{"class": "u-url p-name", "href": templateGet(meta, "checkin.properties.url")},
 					lmth.Text(templateGet(meta, "checkin.properties.name")),
 				),
@@ -86,6 +76,10 @@ func entry(meta map[string][]any) lmth.Node {
 				),
 			),
 		))
+	default:
+		if t.Of != "" {
+			nodes = append(nodes, entryH2(meta, t.Verb, t.Of))
+		}
 	}
 
 	nodes = append(nodes, hCite(templateCite(meta)))
@@ -99,16 +93,16 @@ func entry(meta map[string][]any) lmth.Node {
 	}
 
 	for _, photo := range meta["photo"] {
-		if mfutil.Has(photo, "value") {
-			nodes = append(nodes, Img(lmth.Attr{"src": templateGet(photo, "value"), "alt": templateGet(photo, "alt")}))
-		} else {
-			nodes = append(nodes, Img(lmth.Attr{"src": photo.(string)}))
-		}
+		nodes = append(nodes, photoImg(photo))
+	}
+
+	if t.Type == "event" {
+		nodes = append(nodes, eventDetails(meta))
 	}
 
 	if mfutil.Has(meta, "content") {
 		class := "e-content"
-		if templateGet(meta, "hx-kind") == "note" {
+		if t.Type == "note" {
 			class += " p-name"
 		}
 
@@ -141,6 +135,24 @@ func templateGetOr(m map[string][]any, key string, or string) string {
 	return or
 }
 
+// entryClass returns the class for the element containing an entry, giving its
+// microformats type and kind.
+func entryClass(meta map[string][]any) string {
+	t := postType(meta)
+
+	return "h-" + t.H + " " + t.Type
+}
+
+// photoImg renders a photo given either as a url, or as an object with a value
+// and alt text.
+func photoImg(photo any) lmth.Node {
+	if mfutil.Has(photo, "value") {
+		return Img(lmth.Attr{"class": "u-photo", "src": templateGet(photo, "value"), "alt": templateGet(photo, "alt")})
+	}
+
+	return Img(lmth.Attr{"class": "u-photo", "src": conv[string](photo)})
+}
+
 func templateContent(m any) lmth.Node {
 	if mfutil.Has(m, "content.html") {
 		return lmth.RawText(conv[string](mfutil.Get(m, "content.html")))
@@ -47,7 +47,7 @@ func entryGrouping(grouping GroupedPosts) lmth.Node {
 			),
 		)
 	} else {
-		return Article(lmth.Attr{"class": "h-entry " + templateGet(grouping.Meta, "hx-kind")},
+		return Article(lmth.Attr{"class": entryClass(grouping.Meta)},
 			lmth.Join(
 				entry(grouping.Meta),
 				entryMeta(grouping.Meta),
@@ -0,0 +1,112 @@
+package page
+
+import (
+	"fmt"
+
+	"hawx.me/code/lmth"
+	. "hawx.me/code/lmth/elements"
+	"hawx.me/code/numbersix"
+	"hawx.me/code/tally-ho/internal/mfutil"
+)
+
+// rsvpStatuses are the values of rsvp, in the order they are listed on an
+// event.
+var rsvpStatuses = []string{"yes", "maybe", "interested", "no"}
+
+func eventDetails(meta map[string][]any) lmth.Node {
+	return lmth.Join(
+		P(lmth.Attr{"class": "event"},
+			lmth.Text("from "),
+			Time(lmth.Attr{"class": "dt-start", "datetime": templateGet(meta, "start")},
+				lmth.Text(templateHumanDateTime(meta, "start")),
+			),
+			lmth.Toggle(mfutil.Has(meta, "end"), lmth.Join(
+				lmth.Text(" to "),
+				Time(lmth.Attr{"class": "dt-end", "datetime": templateGet(meta, "end")},
+					lmth.Text(templateHumanDateTime(meta, "end")),
+				),
+			)),
+			lmth.Toggle(mfutil.Has(meta, "location"), lmth.Join(
+				lmth.Text(" at "),
+				eventLocation(meta),
+			)),
+		),
+		lmth.Toggle(mfutil.Has(meta, "summary"),
+			P(lmth.Attr{"class": "p-summary"},
+				lmth.Text(templateGet(meta, "summary")),
+			),
+		),
+	)
+}
+
+func eventLocation(meta map[string][]any) lmth.Node {
+	if !mfutil.Has(meta, "location.properties") {
+		return Span(lmth.Attr{"class": "p-location"},
+			lmth.Text(templateGet(meta, "location")),
+		)
+	}
+
+	name := templateGetOr(meta, "location.properties.name", templateGet(meta, "location.properties.street-address"))
+
+	if mfutil.Has(meta, "location.properties.url") {
+		return A(lmth.Attr{"class": "p-location h-card p-name u-url", "href": templateGet(meta, "location.properties.url")},
+			lmth.Text(name),
+		)
+	}
+
+	return Span(lmth.Attr{"class": "p-location h-card"},
+		Span(lmth.Attr{"class": "p-name"}, lmth.Text(name)),
+	)
+}
+
+// rsvps lists the people who have responded to an event, grouped by their
+// response.
+func rsvps(mentions []numbersix.Group) lmth.Node {
+	responses := map[string][]numbersix.Group{}
+	for _, mention := range mentions {
+		if status := templateGet(mention.Properties, "rsvp"); status != "" {
+			responses[status] = append(responses[status], mention)
+		}
+	}
+
+	var nodes []lmth.Node
+	for _, status := range rsvpStatuses {
+		if len(responses[status]) == 0 {
+			continue
+		}
+
+		people := []lmth.Node{
+			Strong(lmth.Attr{}, lmth.Text(fmt.Sprintf("%s (%d): ", formatHumanRSVP(status), len(responses[status])))),
+		}
+		for i, mention := range responses[status] {
+			if i > 0 {
+				people = append(people, lmth.Text(", "))
+			}
+
+			people = append(people, A(lmth.Attr{"class": "h-card", "href": mention.Subject},
+				lmth.Text(mentionAuthor(mention)),
+			))
+		}
+
+		nodes = append(nodes, P(lmth.Attr{"class": "rsvp " + status}, people...))
+	}
+
+	if len(nodes) == 0 {
+		return lmth.Text("")
+	}
+
+	return Div(lmth.Attr{"class": "expanded meta rsvps"}, nodes...)
+}
+
+// mentionAuthor returns the name to show for the author of a mention.
+func mentionAuthor(mention numbersix.Group) string {
+	if mfutil.Has(mention.Properties, "author.properties.name") {
+		return templateGet(mention.Properties, "author.properties.name")
+	}
+
+	if mfutil.Has(mention.Properties, "author.properties.url") {
+		return templateGet(mention.Properties, "author.properties.url")
+	}
+
+	return mention.Subject
+}
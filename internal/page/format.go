@@ -1,9 +1,11 @@
 package page
 
 import (
+	"strings"
 	"time"
 
 	"hawx.me/code/tally-ho/internal/mfutil"
+	"hawx.me/code/tally-ho/internal/posttype"
 )
 
 func conv[T any](x any) T {
@@ -12,38 +14,13 @@ func conv[T any](x any) T {
 }
 
 func DecideTitle(m map[string][]any) string {
-	prefix := ""
-	defalt := "a post"
+	t := postType(m)
 
-	switch mfutil.Get(m, "hx-kind").(string) {
+	switch t.Type {
+	case "review":
+		return "reviewed " + reviewItemName(m) + " " + formatRating(m)
 	case "rsvp":
 		return formatHumanRSVP(templateGet(m, "rsvp")) + " to " + templateGetOr(m, "name", "an event")
-	case "reply":
-		return "replied to " + conv[string](mfutil.Get(m,
-			"in-reply-to.properties.name",
-			"in-reply-to.properties.url",
-			"in-reply-to"))
-	case "repost":
-		return "reposted " + conv[string](mfutil.Get(m,
-			"repost-of.properties.name",
-			"repost-of.properties.url",
-			"repost-of"))
-	case "like":
-		return "liked " + conv[string](mfutil.Get(m,
-			"like-of.properties.name",
-			"like-of.properties.url",
-			"like-of"))
-	case "bookmark":
-		return "bookmarked " + conv[string](mfutil.Get(m,
-			"bookmark-of.properties.name",
-			"bookmark-of.properties.url",
-			"bookmark-of"))
-	case "video":
-		prefix = "video: "
-		defalt = "a video"
-	case "photo":
-		prefix = "photo: "
-		defalt = "a photo"
 	case "read":
 		if mfutil.Has(m, "read-of.properties.author") {
 			return formatReadStatus(templateGet(m, "read-status")) + " " +
@@ -52,17 +29,39 @@ func DecideTitle(m map[string][]any) string {
 		}
 		return formatReadStatus(templateGet(m, "read-status")) + " " +
 			conv[string](mfutil.Get(m, "read-of.properties.name"))
-	case "drank":
-		return "drank " + conv[string](mfutil.Get(m, "drank.properties.name"))
-	case "checkin":
-		return "checked in to " + conv[string](mfutil.Get(m, "checkin.properties.name"))
+	}
+
+	if t.Of != "" {
+		return t.Verb + " " + conv[string](mfutil.Get(m,
+			t.Of+".properties.name",
+			t.Of+".properties.url",
+			t.Of))
 	}
 
 	if name, ok := mfutil.Get(m, "name", "content.text", "content").(string); ok {
-		return prefix + name
+		return t.Prefix + name
+	}
+
+	return t.Untitled
+}
+
+// postType returns the type that m was discovered to be when it was saved.
+func postType(m map[string][]any) posttype.Type {
+	if t, ok := posttype.Get(templateGet(m, "hx-kind")); ok {
+		return t
+	}
+
+	t, _ := posttype.Get(posttype.Discover(m))
+	return t
+}
+
+// kindName returns the name to show for a hx-kind.
+func kindName(kind string) string {
+	if t, ok := posttype.Get(kind); ok {
+		return strings.ToLower(t.Name)
 	}
 
-	return defalt
+	return kind
 }
 
 func formatHumanDate(s string) string {
@@ -89,6 +88,8 @@ func formatHumanRSVP(s string) string {
 		return "going"
 	case "no":
 		return "not going"
+	case "interested":
+		return "interested"
 	default:
 		return "might be going"
 	}
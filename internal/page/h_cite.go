@@ -13,7 +13,7 @@ func hCite(meta map[string]any) lmth.Node {
 
 	return Div(lmth.Attr{"class": "h-cite"},
 		lmth.Toggle(len(mfutil.GetAll(meta, "photo")) == 1,
-			Img(lmth.Attr{"src": templateGet(meta, "photo")}),
+			lmth.Map(photoImg, mfutil.GetAll(meta, "photo")),
 		),
 		lmth.Toggle(mfutil.Has(meta, "author.properties.name"),
 			P(lmth.Attr{"class": "p-author h-card"},
@@ -24,9 +24,7 @@ func hCite(meta map[string]any) lmth.Node {
 			),
 		),
 		lmth.Toggle(len(mfutil.GetAll(meta, "photo")) > 1,
-			lmth.Map(func(s any) lmth.Node {
-				return Img(lmth.Attr{"src": s.(string)})
-			}, mfutil.GetAll(meta, "photo")),
+			lmth.Map(photoImg, mfutil.GetAll(meta, "photo")),
 		),
 		lmth.Toggle(mfutil.Has(meta, "content"),
 			Div(lmth.Attr{"class": "e-content"},
@@ -0,0 +1,84 @@
+package page
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"hawx.me/code/lmth"
+	. "hawx.me/code/lmth/elements"
+)
+
+type HistoryData struct {
+	Entry map[string][]any
+	// Revisions are expected to be ordered newest first
+	Revisions []Revision
+}
+
+// A Revision lists the properties that were changed when a version of an entry
+// was saved.
+type Revision struct {
+	Version   int
+	CreatedAt time.Time
+	Changes   []PropertyChange
+}
+
+// A PropertyChange lists the values of a property that were removed and added.
+type PropertyChange struct {
+	Property string
+	Removed  []any
+	Added    []any
+}
+
+func History(ctx Context, data HistoryData) lmth.Node {
+	title := "history of " + DecideTitle(data.Entry)
+
+	return Html(lmth.Attr{"lang": "en"},
+		postsHead(ctx, templateTruncate(title, 70),
+			Meta(lmth.Attr{"name": "robots", "content": "noindex"}),
+		),
+		Body(lmth.Attr{},
+			nav(ctx),
+			buttons(A(lmth.Attr{"href": templateGet(data.Entry, "url")}, lmth.Text("↑ Back to post"))),
+			Main(lmth.Attr{},
+				lmth.Toggle(len(data.Revisions) == 0,
+					P(lmth.Attr{}, lmth.Text("No revisions have been recorded for this post.")),
+				),
+				lmth.Map(func(revision Revision) lmth.Node {
+					return Section(lmth.Attr{"class": "revision", "id": fmt.Sprintf("revision-%d", revision.Version)},
+						H2(lmth.Attr{},
+							lmth.Text(fmt.Sprintf("revision %d, ", revision.Version)),
+							Time(lmth.Attr{"datetime": revision.CreatedAt.Format(time.RFC3339)},
+								lmth.Text(revision.CreatedAt.Format("January 02, 2006 at 15:04")),
+							),
+						),
+						Dl(lmth.Attr{},
+							lmth.Map(func(change PropertyChange) lmth.Node {
+								return lmth.Join(
+									Dt(lmth.Attr{}, lmth.Text(change.Property)),
+									lmth.Map(func(value any) lmth.Node {
+										return Dd(lmth.Attr{}, Del(lmth.Attr{}, lmth.Text(revisionValue(value))))
+									}, change.Removed),
+									lmth.Map(func(value any) lmth.Node {
+										return Dd(lmth.Attr{}, Ins(lmth.Attr{}, lmth.Text(revisionValue(value))))
+									}, change.Added),
+								)
+							}, revision.Changes),
+						),
+					)
+				}, data.Revisions),
+			),
+		),
+		pageFooter(ctx),
+	)
+}
+
+// revisionValue formats a property value so it can be compared with others.
+func revisionValue(value any) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+
+	data, _ := json.Marshal(value)
+	return string(data)
+}
@@ -1,16 +1,22 @@
 package page
 
 import (
+	"net/url"
+
 	"hawx.me/code/lmth"
 	. "hawx.me/code/lmth/elements"
 )
 
 type ListData struct {
 	GroupedPosts []GroupedPosts
-	OlderThan    string
-	ShowLatest   bool
-	Kind         string
-	Category     string
+	// NewerURL and OlderURL link to the pages either side of this one, they are
+	// empty when there are no more posts in that direction
+	NewerURL string
+	OlderURL string
+	// ShowLatest links back to the latest posts, when not showing them
+	ShowLatest bool
+	Kind       string
+	Category   string
 }
 
 type GroupedPosts struct {
@@ -23,13 +29,16 @@ func List(ctx Context, data ListData) lmth.Node {
 	var bodyNodes []lmth.Node
 
 	buttonsLeft := buttonsEmpty()
+	alternates := lmth.Text("")
 	if data.Kind != "" {
+		alternates = feedAlternates(ctx, "kind/"+data.Kind+"/", ctx.Name+" "+kindName(data.Kind)+" posts")
 		buttonsLeft = Span(lmth.Attr{"class": "page"},
 			lmth.Text("kind "),
-			Strong(lmth.Attr{}, lmth.Text(data.Kind)),
+			Strong(lmth.Attr{}, lmth.Text(kindName(data.Kind))),
 		)
 	}
 	if data.Category != "" {
+		alternates = feedAlternates(ctx, "category/"+url.PathEscape(data.Category)+"/", ctx.Name+" posts in "+data.Category)
 		buttonsLeft = Span(lmth.Attr{"class": "page"},
 			lmth.Text("category "),
 			Strong(lmth.Attr{}, lmth.Text(data.Category)),
@@ -37,7 +46,7 @@ func List(ctx Context, data ListData) lmth.Node {
 	}
 
 	var bottomButtons lmth.Node
-	if data.OlderThan == "NOMORE" {
+	if len(data.GroupedPosts) == 0 {
 		bodyNodes = append(bodyNodes, P(lmth.Attr{},
 			lmth.Text("👏 You have reached the end. Try going back to the "),
 			A(lmth.Attr{"class": "latest", "href": ctx.Path("")}, lmth.Text("Latest")),
@@ -49,11 +58,7 @@ func List(ctx Context, data ListData) lmth.Node {
 		}
 
 		bottomButtons = Div(lmth.Attr{"class": "buttons"},
-			lmth.Toggle(data.OlderThan != "",
-				A(lmth.Attr{"class": "older", "href": "?before=" + data.OlderThan},
-					lmth.Text("← "),
-					Span(lmth.Attr{}, lmth.Text("Older")),
-				)),
+			pageButtons(data.NewerURL, data.OlderURL),
 			lmth.Toggle(data.ShowLatest, A(lmth.Attr{"class": "latest", "href": ctx.Path("")},
 				Span(lmth.Attr{}, lmth.Text("Latest")),
 				lmth.Text(" ⇥"),
@@ -61,7 +66,7 @@ func List(ctx Context, data ListData) lmth.Node {
 	}
 
 	return Html(lmth.Attr{"lang": "en"},
-		postsHead(ctx, ctx.Name+" posts"),
+		postsHead(ctx, ctx.Name+" posts", pageLinks(data.NewerURL, data.OlderURL), alternates),
 		Body(lmth.Attr{},
 			nav(ctx),
 			buttons(buttonsLeft),
@@ -74,17 +79,48 @@ func List(ctx Context, data ListData) lmth.Node {
 	)
 }
 
+// pageLinks relates a page in a list to those either side of it, as the list is
+// newest first the next page has older posts.
+func pageLinks(newerURL, olderURL string) lmth.Node {
+	return lmth.Join(
+		lmth.Toggle(newerURL != "", Link(lmth.Attr{"rel": "prev", "href": newerURL})),
+		lmth.Toggle(olderURL != "", Link(lmth.Attr{"rel": "next", "href": olderURL})),
+	)
+}
+
+func pageButtons(newerURL, olderURL string) lmth.Node {
+	return lmth.Join(
+		lmth.Toggle(olderURL != "",
+			A(lmth.Attr{"class": "older", "rel": "next", "href": olderURL},
+				lmth.Text("← "),
+				Span(lmth.Attr{}, lmth.Text("Older")),
+			)),
+		lmth.Toggle(newerURL != "",
+			A(lmth.Attr{"class": "newer", "rel": "prev", "href": newerURL},
+				Span(lmth.Attr{}, lmth.Text("Newer")),
+				lmth.Text(" →"),
+			)),
+	)
+}
+
 func postsHead(ctx Context, title string, nodes ...lmth.Node) lmth.Node {
 	def := []lmth.Node{
 		Link(lmth.Attr{"rel": "webmention", "href": ctx.Path("-/webmention")}),
-		Link(lmth.Attr{"rel": "alternative", "href": ctx.Path("feed/atom"), "type": "application/atom+xml"}),
-		Link(lmth.Attr{"rel": "alternative", "href": ctx.Path("feed/jsonfee"), "type": "application/json"}),
-		Link(lmth.Attr{"rel": "alternative", "href": ctx.Path("feed/rss"), "type": "application/rss+xml"}),
+		feedAlternates(ctx, "", ctx.Name+" posts"),
 	}
 
 	return pageHead(ctx, title, append(def, nodes...)...)
 }
 
+// feedAlternates links to the feeds for the list at path, in each format.
+func feedAlternates(ctx Context, path, title string) lmth.Node {
+	return lmth.Join(
+		Link(lmth.Attr{"rel": "alternate", "href": ctx.Path(path + "feed/atom"), "type": "application/atom+xml", "title": title}),
+		Link(lmth.Attr{"rel": "alternate", "href": ctx.Path(path + "feed/jsonfeed"), "type": "application/json", "title": title}),
+		Link(lmth.Attr{"rel": "alternate", "href": ctx.Path(path + "feed/rss"), "type": "application/rss+xml", "title": title}),
+	)
+}
+
 func pageHead(ctx Context, title string, nodes ...lmth.Node) lmth.Node {
 	def := []lmth.Node{
 		Meta(lmth.Attr{"charset": "utf-8"}),
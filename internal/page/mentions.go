@@ -8,16 +8,19 @@ import (
 )
 
 type MentionsData struct {
-	Title      string
-	Items      []numbersix.Group
-	OlderThan  string
+	Title string
+	Items []numbersix.Group
+	// NewerURL and OlderURL link to the pages either side of this one, they are
+	// empty when there are no more mentions in that direction
+	NewerURL   string
+	OlderURL   string
 	ShowLatest bool
 }
 
 func Mentions(ctx Context, data MentionsData) lmth.Node {
 	var bodyNodes lmth.Node
 
-	if data.OlderThan == "NOMORE" {
+	if len(data.Items) == 0 {
 		bodyNodes = P(lmth.Attr{},
 			lmth.Text("👏 You have reached the end. Try going back to the "),
 			A(lmth.Attr{"class": "latest", "href": ctx.Path("mentions")},
@@ -61,7 +64,7 @@ func Mentions(ctx Context, data MentionsData) lmth.Node {
 	}
 
 	return Html(lmth.Attr{"lang": "en"},
-		postsHead(ctx, data.Title),
+		postsHead(ctx, data.Title, pageLinks(data.NewerURL, data.OlderURL)),
 		Body(lmth.Attr{},
 			nav(ctx),
 			buttons(Span(lmth.Attr{"class": "page"}, lmth.Text("mentions"))),
@@ -69,12 +72,7 @@ func Mentions(ctx Context, data MentionsData) lmth.Node {
 				bodyNodes,
 			),
 			Nav(lmth.Attr{"class": "buttons"},
-				lmth.Toggle(data.OlderThan != "",
-					A(lmth.Attr{"class": "older", "href": "?before=" + data.OlderThan},
-						lmth.Text("← "),
-						Span(lmth.Attr{}, lmth.Text("Older")),
-					),
-				),
+				pageButtons(data.NewerURL, data.OlderURL),
 				lmth.Toggle(data.ShowLatest,
 					A(lmth.Attr{"class": "latest", "href": ctx.Path("mentions")},
 						Span(lmth.Attr{}, lmth.Text("Latest")),
@@ -2,6 +2,7 @@ package page
 
 import (
 	"fmt"
+	"net/url"
 	"strings"
 	"time"
 
@@ -49,7 +50,7 @@ func Post(ctx Context, data PostData) lmth.Node {
 			lmth.Text("filed under "),
 			lmth.Map2(func(i int, category any) lmth.Node {
 				return lmth.Join(
-					A(lmth.Attr{"class": "p-category", "href": ctx.Path("category/" + category.(string))},
+					A(lmth.Attr{"class": "p-category", "href": ctx.Path("category/" + url.PathEscape(category.(string)))},
 						lmth.Text(category.(string)),
 					),
 					lmth.Toggle(i != len(cat)-1, lmth.Text(", ")),
@@ -63,21 +64,33 @@ func Post(ctx Context, data PostData) lmth.Node {
 			Meta(lmth.Attr{"property": "og:type", "content": "website"}),
 			Meta(lmth.Attr{"property": "og:title", "content": DecideTitle(data.Entry)}),
 			Meta(lmth.Attr{"property": "og:url", "content": templateGet(data.Entry, "url")}),
+			lmth.Toggle(mfutil.Has(meta, "hx-unlisted"),
+				Meta(lmth.Attr{"name": "robots", "content": "noindex"}),
+			),
 		),
 		Body(lmth.Attr{},
 			nav(ctx),
 			buttons(buttonsBackToPosts(ctx)),
 			Main(lmth.Attr{},
-				Article(lmth.Attr{"class": "h-entry " + templateGet(meta, "hx-kind")},
+				Article(lmth.Attr{"class": entryClass(meta)},
 					lmth.Join(
 						entry(data.Posts.Meta),
 						Div(lmth.Attr{"class": "expanded meta"},
 							Div(lmth.Attr{},
 								A(lmth.Attr{"href": ctx.Path("kind/" + templateGet(meta, "hx-kind"))},
-									lmth.Text(templateGet(meta, "hx-kind")),
+									lmth.Text(kindName(templateGet(meta, "hx-kind"))),
 								),
 								lmth.Text(" "),
 								publishedUpdated(meta),
+								lmth.Toggle(mfutil.Has(meta, "updated"),
+									lmth.Join(
+										lmth.Text(" ("),
+										A(lmth.Attr{"href": ctx.Path("entry/" + templateGet(meta, "uid") + "/history")},
+											lmth.Text("history"),
+										),
+										lmth.Text(")"),
+									),
+								),
 							),
 							Div(lmth.Attr{},
 								lmth.Text("by "),
@@ -95,6 +108,9 @@ func Post(ctx Context, data PostData) lmth.Node {
 							syndication(),
 							category(),
 						),
+						lmth.Toggle(templateGet(meta, "hx-kind") == "event",
+							rsvps(data.Mentions),
+						),
 						lmth.Toggle(len(data.Mentions) > 0,
 							Details(lmth.Attr{"class": "meta"},
 								Summary(lmth.Attr{},
@@ -103,7 +119,9 @@ func Post(ctx Context, data PostData) lmth.Node {
 								Ol(lmth.Attr{},
 									lmth.Map(func(mention numbersix.Group) lmth.Node {
 										name := "mentioned by "
-										if mfutil.Has(mention.Properties, "in-reply-to") {
+										if mfutil.Has(mention.Properties, "rsvp") {
+											name = "rsvp from "
+										} else if mfutil.Has(mention.Properties, "in-reply-to") {
 											name = "reply from "
 										} else if mfutil.Has(mention.Properties, "repost-of") {
 											name = "reposted by "
@@ -0,0 +1,88 @@
+package page
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"hawx.me/code/lmth"
+	. "hawx.me/code/lmth/elements"
+	"hawx.me/code/tally-ho/internal/mfutil"
+)
+
+// maxStars is the most stars that will be shown for a rating, any scale larger
+// than this is shown as the rating out of best instead.
+const maxStars = 10
+
+func reviewSummary(meta map[string][]any) lmth.Node {
+	itemType := strings.TrimPrefix(templateGet(meta, "item.type"), "h-")
+	if itemType == "" {
+		itemType = "cite"
+	}
+
+	return lmth.Join(
+		H2(lmth.Attr{"class": "p-summary"},
+			lmth.Text("reviewed "),
+			A(lmth.Attr{"class": "p-item h-" + itemType, "href": reviewItemURL(meta)},
+				Span(lmth.Attr{"class": "p-name"}, lmth.Text(reviewItemName(meta))),
+			),
+		),
+		P(lmth.Attr{"class": "rating"},
+			Data(lmth.Attr{"class": "p-rating", "value": templateGet(meta, "rating")},
+				lmth.Text(formatRating(meta)),
+			),
+			Data(lmth.Attr{"class": "p-worst hidden", "value": formatNumber(ratingScale(meta, "worst", 1))}),
+			Data(lmth.Attr{"class": "p-best hidden", "value": formatNumber(ratingScale(meta, "best", 5))}),
+		),
+	)
+}
+
+func reviewItemURL(meta map[string][]any) string {
+	if u, ok := mfutil.Get(meta, "item.properties.url", "item").(string); ok {
+		return u
+	}
+
+	return ""
+}
+
+func reviewItemName(meta map[string][]any) string {
+	if name, ok := mfutil.Get(meta, "item.properties.name").(string); ok {
+		return name
+	}
+
+	return reviewItemURL(meta)
+}
+
+// formatRating shows a rating as stars, such as ★★★★☆ for 4 out of 5.
+func formatRating(meta map[string][]any) string {
+	rating, err := strconv.ParseFloat(templateGet(meta, "rating"), 64)
+	if err != nil {
+		return templateGet(meta, "rating")
+	}
+
+	best := ratingScale(meta, "best", 5)
+	worst := ratingScale(meta, "worst", 1)
+
+	if best > maxStars || best <= worst {
+		return formatNumber(rating) + " out of " + formatNumber(best)
+	}
+
+	stars := int(best)
+	filled := int(math.Round(rating))
+	filled = max(0, min(filled, stars))
+
+	return strings.Repeat("★", filled) + strings.Repeat("☆", stars-filled)
+}
+
+func ratingScale(meta map[string][]any, key string, defalt float64) float64 {
+	if f, err := strconv.ParseFloat(templateGet(meta, key), 64); err == nil {
+		return f
+	}
+
+	return defalt
+}
+
+func formatNumber(f float64) string {
+	return fmt.Sprint(f)
+}
@@ -0,0 +1,42 @@
+package page
+
+import (
+	"hawx.me/code/lmth"
+	. "hawx.me/code/lmth/elements"
+)
+
+type SearchData struct {
+	Query        string
+	GroupedPosts []GroupedPosts
+}
+
+func Search(ctx Context, data SearchData) lmth.Node {
+	title := "search"
+	if data.Query != "" {
+		title = "search for " + data.Query
+	}
+
+	return Html(lmth.Attr{"lang": "en"},
+		postsHead(ctx, templateTruncate(title, 70),
+			Meta(lmth.Attr{"name": "robots", "content": "noindex"}),
+		),
+		Body(lmth.Attr{},
+			nav(ctx),
+			buttons(Span(lmth.Attr{"class": "page"},
+				lmth.Text("search "),
+				Strong(lmth.Attr{}, lmth.Text(data.Query)),
+			)),
+			Main(lmth.Attr{},
+				Form(lmth.Attr{"class": "search", "action": ctx.Path("search"), "method": "get"},
+					Input(lmth.Attr{"type": "search", "name": "q", "value": data.Query, "aria-label": "search"}),
+					Button(lmth.Attr{"type": "submit"}, lmth.Text("Search")),
+				),
+				lmth.Toggle(data.Query != "" && len(data.GroupedPosts) == 0,
+					P(lmth.Attr{}, lmth.Text("Nothing was found.")),
+				),
+				lmth.Map(entryGrouping, data.GroupedPosts),
+			),
+		),
+		pageFooter(ctx),
+	)
+}
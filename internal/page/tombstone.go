@@ -0,0 +1,45 @@
+package page
+
+import (
+	"time"
+
+	"hawx.me/code/lmth"
+	. "hawx.me/code/lmth/elements"
+)
+
+type TombstoneData struct {
+	URL string
+	// Deleted is the time the entry was deleted, it may be zero for entries
+	// deleted before this was recorded
+	Deleted time.Time
+}
+
+// Tombstone is shown in place of an entry that has been deleted, so that anyone
+// following a link to it, or checking a webmention, can see that it is gone.
+func Tombstone(ctx Context, data TombstoneData) lmth.Node {
+	return Html(lmth.Attr{"lang": "en"},
+		postsHead(ctx, "gone",
+			Meta(lmth.Attr{"name": "robots", "content": "noindex"}),
+		),
+		Body(lmth.Attr{},
+			nav(ctx),
+			buttons(buttonsBackToPosts(ctx)),
+			Main(lmth.Attr{},
+				Article(lmth.Attr{"class": "h-entry"},
+					P(lmth.Attr{},
+						lmth.Text("This post has been deleted"),
+						lmth.Toggle(!data.Deleted.IsZero(), lmth.Join(
+							lmth.Text(" on "),
+							Time(lmth.Attr{"class": "dt-deleted", "datetime": data.Deleted.Format(time.RFC3339)},
+								lmth.Text(data.Deleted.Format("January 02, 2006 at 15:04")),
+							),
+						)),
+						lmth.Text("."),
+					),
+					A(lmth.Attr{"class": "u-url hidden", "href": data.URL}, lmth.Text(data.URL)),
+				),
+			),
+		),
+		pageFooter(ctx),
+	)
+}
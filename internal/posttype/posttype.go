@@ -0,0 +1,239 @@
+// Package posttype lists the kinds of post that can be created, so that
+// discovering the kind of an entry, rendering it and advertising it to micropub
+// clients all agree.
+//
+// See https://indieweb.org/post-type-discovery.
+package posttype
+
+import "slices"
+
+// A Type is a kind of post.
+type Type struct {
+	// Type is the value stored as hx-kind, and used in /kind/:kind.
+	Type string `json:"type"`
+
+	// Name is a human readable name for the type.
+	Name string `json:"name"`
+
+	// H is the microformats type, without the h- prefix, used for the post.
+	H string `json:"h"`
+
+	// Properties lists the properties used by this type of post.
+	Properties []string `json:"properties"`
+
+	// RequiredProperties lists the properties that must be given for an entry
+	// to be discovered as this type of post.
+	RequiredProperties []string `json:"required-properties,omitempty"`
+
+	// Verb and Of title a post that is about something else, as the verb
+	// followed by the name of the thing given in the Of property. So a like is
+	// titled "liked" and the name of its like-of.
+	Verb string `json:"-"`
+	Of   string `json:"-"`
+
+	// Prefix comes before the name or content of a post of this type in its
+	// title, and Untitled is the title used when there is neither.
+	Prefix   string `json:"-"`
+	Untitled string `json:"-"`
+
+	matches func(data map[string][]any) bool
+}
+
+// RSVPs are the values that an rsvp property can have.
+var RSVPs = []string{"yes", "no", "maybe", "interested"}
+
+// Types are listed in the order that they are checked by Discover, the last
+// type is used when no others match.
+var Types = []Type{
+	{
+		Type:               "event",
+		Name:               "Event",
+		H:                  "event",
+		Properties:         []string{"name", "start", "end", "location", "summary", "content"},
+		RequiredProperties: []string{"name", "start"},
+		Untitled:           "an event",
+		matches: func(data map[string][]any) bool {
+			return len(data["h"]) > 0 && data["h"][0] == "event"
+		},
+	},
+	{
+		Type:               "review",
+		Name:               "Review",
+		H:                  "review",
+		Properties:         []string{"item", "rating", "best", "worst", "name", "content"},
+		RequiredProperties: []string{"item", "rating"},
+		matches: func(data map[string][]any) bool {
+			return len(data["h"]) > 0 && data["h"][0] == "review"
+		},
+	},
+	{
+		Type:               "rsvp",
+		Name:               "RSVP",
+		H:                  "entry",
+		Properties:         []string{"rsvp", "in-reply-to", "name", "content"},
+		RequiredProperties: []string{"rsvp", "in-reply-to"},
+		matches: func(data map[string][]any) bool {
+			if len(data["rsvp"]) == 0 {
+				return false
+			}
+
+			rsvp, _ := data["rsvp"][0].(string)
+			return slices.Contains(RSVPs, rsvp)
+		},
+	},
+	{
+		Type:               "reply",
+		Name:               "Reply",
+		H:                  "entry",
+		Properties:         []string{"in-reply-to", "content"},
+		RequiredProperties: []string{"in-reply-to"},
+		Verb:               "replied to",
+		Of:                 "in-reply-to",
+	},
+	{
+		Type:               "repost",
+		Name:               "Repost",
+		H:                  "entry",
+		Properties:         []string{"repost-of", "content"},
+		RequiredProperties: []string{"repost-of"},
+		Verb:               "reposted",
+		Of:                 "repost-of",
+	},
+	{
+		Type:               "like",
+		Name:               "Like",
+		H:                  "entry",
+		Properties:         []string{"like-of"},
+		RequiredProperties: []string{"like-of"},
+		Verb:               "liked",
+		Of:                 "like-of",
+	},
+	{
+		Type:               "bookmark",
+		Name:               "Bookmark",
+		H:                  "entry",
+		Properties:         []string{"bookmark-of", "name", "content"},
+		RequiredProperties: []string{"bookmark-of"},
+		Verb:               "bookmarked",
+		Of:                 "bookmark-of",
+	},
+	{
+		Type:               "video",
+		Name:               "Video",
+		H:                  "entry",
+		Properties:         []string{"video", "content"},
+		RequiredProperties: []string{"video"},
+		Prefix:             "video: ",
+		Untitled:           "a video",
+	},
+	{
+		Type:               "photo",
+		Name:               "Photo",
+		H:                  "entry",
+		Properties:         []string{"photo", "content"},
+		RequiredProperties: []string{"photo"},
+		Prefix:             "photo: ",
+		Untitled:           "a photo",
+	},
+	{
+		Type:               "audio",
+		Name:               "Audio",
+		H:                  "entry",
+		Properties:         []string{"audio", "content"},
+		RequiredProperties: []string{"audio"},
+		Prefix:             "audio: ",
+		Untitled:           "an audio post",
+	},
+	{
+		Type:               "read",
+		Name:               "Read",
+		H:                  "entry",
+		Properties:         []string{"read-of", "read-status", "content"},
+		RequiredProperties: []string{"read-of"},
+	},
+	{
+		Type:               "drank",
+		Name:               "Drank",
+		H:                  "entry",
+		Properties:         []string{"drank", "content"},
+		RequiredProperties: []string{"drank"},
+		Verb:               "drank",
+		Of:                 "drank",
+	},
+	{
+		Type:               "ate",
+		Name:               "Ate",
+		H:                  "entry",
+		Properties:         []string{"ate", "content"},
+		RequiredProperties: []string{"ate"},
+		Verb:               "ate",
+		Of:                 "ate",
+	},
+	{
+		Type:               "checkin",
+		Name:               "Checkin",
+		H:                  "entry",
+		Properties:         []string{"checkin", "content"},
+		RequiredProperties: []string{"checkin"},
+		Verb:               "checked in to",
+		Of:                 "checkin",
+	},
+	{
+		Type:               "article",
+		Name:               "Article",
+		H:                  "entry",
+		Properties:         []string{"name", "content", "summary"},
+		RequiredProperties: []string{"name"},
+		Untitled:           "a post",
+	},
+	{
+		Type:               "note",
+		Name:               "Note",
+		H:                  "entry",
+		Properties:         []string{"content"},
+		RequiredProperties: []string{"content"},
+		Untitled:           "a post",
+		matches: func(data map[string][]any) bool {
+			return true
+		},
+	},
+}
+
+// Discover returns the type of post that data is.
+//
+// I know the algorithm https://indieweb.org/post-type-discovery does more
+// than this, that is for another time
+func Discover(data map[string][]any) string {
+	for _, t := range Types {
+		if t.Matches(data) {
+			return t.Type
+		}
+	}
+
+	return Types[len(Types)-1].Type
+}
+
+// Matches returns true if data has the properties required for the type.
+func (t Type) Matches(data map[string][]any) bool {
+	if t.matches != nil {
+		return t.matches(data)
+	}
+
+	for _, property := range t.RequiredProperties {
+		if len(data[property]) == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Get returns the type with the given name.
+func Get(kind string) (Type, bool) {
+	i := slices.IndexFunc(Types, func(t Type) bool { return t.Type == kind })
+	if i < 0 {
+		return Type{}, false
+	}
+
+	return Types[i], true
+}
@@ -0,0 +1,48 @@
+package posttype
+
+import (
+	"testing"
+
+	"hawx.me/code/assert"
+)
+
+func TestDiscover(t *testing.T) {
+	testCases := map[string]map[string][]any{
+		"event":    {"h": {"event"}, "name": {"A meetup"}, "start": {"2020-10-01T18:00:00+01:00"}},
+		"review":   {"h": {"review"}, "item": {"https://example.com/book"}, "rating": {"4"}},
+		"rsvp":     {"rsvp": {"yes"}, "in-reply-to": {"https://example.com/event"}},
+		"reply":    {"rsvp": {"going"}, "in-reply-to": {"https://example.com/post"}},
+		"repost":   {"repost-of": {"https://example.com/"}},
+		"like":     {"like-of": {"https://example.com/"}},
+		"bookmark": {"bookmark-of": {"https://example.com/"}, "name": {"A thing"}},
+		"video":    {"video": {"https://example.com/a.mp4"}, "photo": {"https://example.com/a.jpg"}},
+		"audio":    {"audio": {"https://example.com/a.mp3"}, "content": {"Listen"}},
+		"photo":    {"photo": {"https://example.com/a.jpg"}, "content": {"Look"}},
+		"read":     {"read-of": {map[string]any{}}},
+		"drank":    {"drank": {map[string]any{}}},
+		"ate":      {"ate": {map[string]any{}}},
+		"checkin":  {"checkin": {map[string]any{}}},
+		"article":  {"name": {"A title"}, "content": {"Some words"}},
+		"note":     {"content": {"Some words"}},
+	}
+
+	for expected, data := range testCases {
+		t.Run(expected, func(t *testing.T) {
+			assert.Equal(t, expected, Discover(data))
+		})
+	}
+
+	assert.Equal(t, "note", Discover(map[string][]any{}))
+	assert.Equal(t, "rsvp", Discover(map[string][]any{"rsvp": {"interested"}, "in-reply-to": {"https://example.com/event"}}))
+}
+
+func TestGet(t *testing.T) {
+	assert := assert.New(t)
+
+	like, ok := Get("like")
+	assert.True(ok)
+	assert.Equal("Like", like.Name)
+
+	_, ok = Get("what")
+	assert.False(ok)
+}
@@ -27,23 +27,74 @@ import (
 )
 
 func usage() {
-	fmt.Println(`Usage: tally-ho [options]
+	fmt.Println(`Usage: tally-ho [options] [command]
 
 	--config PATH=./config.toml
 	--web DIR=web
 	--db PATH=file::memory
 	--media-dir DIR
 	--port PORT=8080
-	--socket PATH`)
+	--socket PATH
+	--static-out DIR
+	    Write the pages affected by each change to DIR, after using build.
+
+Commands:
+
+	rebuild-search [--site URL]
+	    Recreate the search index from the stored entries.
+
+	build [--site URL] --out DIR
+	    Write every public page to DIR, so it can be hosted as static files.
+
+	export [--site URL] [--format mf2|jf2] [--out PATH]
+	    Write every entry, revision, mention, redirect, tombstone and the
+	    media manifest as JSON.
+
+	import [--site URL] PATH
+	    Read an export in to an empty database, without syndicating or
+	    sending webmentions.
+
+	purge [--site URL] URL
+	    Permanently remove the entry at URL, with its revisions, mentions and
+	    media, after sending webmentions so that copies can be removed. The
+	    URL will continue to respond with 410 Gone.
+
+	redirect [--site URL] add FROM TO
+	redirect [--site URL] remove FROM
+	redirect [--site URL] list
+	    Manage the urls that are permanently redirected elsewhere, such as
+	    those of an older site. The urls can be given relative to the baseURL.
+
+When more than one site is configured --site must be given with the baseURL of
+the site to use. When no command is given the sites are served.`)
 }
 
 type config struct {
+	siteConfig
+
+	// Sites can be given to host more than one site, in which case the options
+	// above are ignored. Requests are routed to the site with the BaseURL that
+	// matches best, so each must be different.
+	Sites []siteConfig
+}
+
+type siteConfig struct {
 	// Me is the URL to your (website containing a) h-card
 	Me string
 	// BaseURL is the URL this will be hosted from, it can contain a path
 	BaseURL string
 	// MediaURL is the URL the media-dir will be hosted from
 	MediaURL string
+	// TablePrefix is added to the names of the tables used for this site, it
+	// must be different for each site when more than one is hosted
+	TablePrefix string
+	// MediaDir is the directory media is written to, by default --media-dir.
+	// When more than one site is hosted the default is a directory within
+	// --media-dir named by the TablePrefix, so that each site has its own
+	MediaDir string
+	// PageSize is the number of posts, or mentions, shown on each page of a
+	// list, by default 25
+	PageSize int
 
 	// Context contains data specifying details shown in the site
 	Context page.Context
@@ -64,6 +115,17 @@ type config struct {
 	}
 }
 
+// site is a blog, and the things it needs to be served.
+type site struct {
+	conf                siteConfig
+	baseURL             *url.URL
+	blog                *blog.Blog
+	fw                  *blog.FileWriter
+	hub                 *websub.Hub
+	idempotencyStore    *blog.IdempotencyStore
+	micropubSyndicateTo []micropub.SyndicateTo
+}
+
 func main() {
 	var (
 		configPath = flag.String("config", "./config.toml", "")
@@ -72,6 +134,7 @@ func main() {
 		mediaDir   = flag.String("media-dir", "", "")
 		port       = flag.String("port", "8080", "")
 		socket     = flag.String("socket", "", "")
+		staticOut  = flag.String("static-out", "", "")
 	)
 	flag.Usage = usage
 	flag.Parse()
@@ -85,26 +148,141 @@ func main() {
 		return
 	}
 
-	baseURL, err := url.Parse(conf.BaseURL)
+	siteConfs := conf.Sites
+	if len(siteConfs) == 0 {
+		siteConfs = []siteConfig{conf.siteConfig}
+	}
+
+	db, err := sql.Open("sqlite3", *dbPath)
 	if err != nil {
-		logger.Error("base url invalid", slog.Any("err", err))
+		logger.Error("error opening sqlite file", slog.String("path", *dbPath), slog.Any("err", err))
 		return
 	}
+	defer db.Close()
 
-	mediaURL, err := url.Parse(conf.MediaURL)
+	hubStore, err := blog.NewHubStore(db)
 	if err != nil {
-		logger.Error("media url invalid", slog.Any("err", err))
+		logger.Error("problem initialising hub store", slog.Any("err", err))
 		return
 	}
 
-	db, err := sql.Open("sqlite3", *dbPath)
-	if err != nil {
-		logger.Error("error opening sqlite file", slog.String("path", *dbPath), slog.Any("err", err))
+	var sites []*site
+	mediaDirs := map[string]bool{}
+	tablePrefixes := map[string]bool{}
+	for _, siteConf := range siteConfs {
+		if tablePrefixes[siteConf.TablePrefix] {
+			logger.Error("each site must have its own table prefix", slog.String("baseURL", siteConf.BaseURL))
+			return
+		}
+		tablePrefixes[siteConf.TablePrefix] = true
+
+		if siteConf.MediaDir == "" {
+			siteConf.MediaDir = *mediaDir
+			if len(siteConfs) > 1 {
+				siteConf.MediaDir = filepath.Join(*mediaDir, siteConf.TablePrefix)
+			}
+		}
+		if mediaDirs[siteConf.MediaDir] {
+			logger.Error("each site must have its own media dir", slog.String("baseURL", siteConf.BaseURL))
+			return
+		}
+		mediaDirs[siteConf.MediaDir] = true
+
+		s, err := newSite(logger, siteConf, db, hubStore)
+		if err != nil {
+			logger.Error("problem initialising site", slog.String("baseURL", siteConf.BaseURL), slog.Any("err", err))
+			return
+		}
+		defer s.blog.Close()
+
+		sites = append(sites, s)
+	}
+
+	if flag.NArg() > 0 {
+		if err := runCommand(flag.Args(), sites); err != nil {
+			logger.Error("problem running command", slog.String("command", flag.Arg(0)), slog.Any("err", err))
+		}
 		return
 	}
 
+	for _, s := range sites {
+		s.blog.Schedule()
+	}
+
+	if *staticOut != "" {
+		for _, s := range sites {
+			dir := *staticOut
+			if len(sites) > 1 {
+				dir = filepath.Join(dir, s.baseURL.Host, filepath.FromSlash(s.baseURL.Path))
+			}
+
+			s.blog.BuildOnChange(dir)
+		}
+	}
+
+	static := http.StripPrefix("/public/",
+		http.FileServer(
+			http.Dir(filepath.Join(*webPath, "static"))))
+
+	if len(sites) == 1 {
+		serve.Serve(*port, *socket, sites[0].handler(static, nil))
+		return
+	}
+
+	handlers := make([]http.Handler, len(sites))
+	for i, s := range sites {
+		handlers[i] = s.handler(static, destinationsFor(s, sites))
+	}
+
+	serve.Serve(*port, *socket, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if i := siteFor(sites, r); i >= 0 {
+			handlers[i].ServeHTTP(w, r)
+			return
+		}
+
+		http.NotFound(w, r)
+	}))
+}
+
+// siteFor returns the index of the site that should handle r, this is the site
+// on the same host with the longest path that r is for. If no site matches -1
+// is returned.
+func siteFor(sites []*site, r *http.Request) int {
+	found, foundLen := -1, -1
+
+	for i, s := range sites {
+		if s.baseURL.Host != r.Host {
+			continue
+		}
+
+		path := strings.TrimSuffix(s.baseURL.Path, "/")
+		if (r.URL.Path == path || strings.HasPrefix(r.URL.Path, path+"/")) && len(path) > foundLen {
+			found, foundLen = i, len(path)
+		}
+	}
+
+	return found
+}
+
+func newSite(logger *slog.Logger, conf siteConfig, db *sql.DB, hubStore *blog.HubStore) (*site, error) {
+	baseURL, err := url.Parse(conf.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("base url invalid: %w", err)
+	}
+
+	mediaURL, err := url.Parse(conf.MediaURL)
+	if err != nil {
+		return nil, fmt.Errorf("media url invalid: %w", err)
+	}
+
+	if conf.MediaDir != "" {
+		if err := os.MkdirAll(conf.MediaDir, 0o755); err != nil {
+			return nil, fmt.Errorf("media dir could not be created: %w", err)
+		}
+	}
+
 	fw := &blog.FileWriter{
-		MediaDir: *mediaDir,
+		MediaDir: conf.MediaDir,
 		MediaURL: mediaURL,
 	}
 
@@ -144,48 +322,82 @@ func main() {
 		}
 	}
 
-	hubStore, err := blog.NewHubStore(db)
+	idempotencyStore, err := blog.NewIdempotencyStore(db, conf.TablePrefix)
 	if err != nil {
-		logger.Error("problem initialising hub store", slog.Any("err", err))
-		return
+		return nil, fmt.Errorf("problem initialising idempotency store: %w", err)
 	}
 
-	mediaEndpointURL, _ := url.Parse("-/media")
 	hubEndpointURL, _ := url.Parse("-/hub")
 
 	websubhub := websub.New(baseURL.ResolveReference(hubEndpointURL).String(), hubStore)
 
 	b, err := blog.New(logger, blog.Config{
-		Me:       conf.Me,
-		BaseURL:  baseURL,
-		MediaURL: mediaURL,
-		HubURL:   baseURL.ResolveReference(hubEndpointURL).String(),
+		Me:          conf.Me,
+		BaseURL:     baseURL,
+		MediaURL:    mediaURL,
+		MediaDir:    conf.MediaDir,
+		HubURL:      baseURL.ResolveReference(hubEndpointURL).String(),
+		TablePrefix: conf.TablePrefix,
+		PageSize:    conf.PageSize,
+		IsOwner:     auth.Owner(conf.Me),
 	}, conf.Context.WithPath(baseURL.Path), db, websubhub, blogSilos)
 	if err != nil {
-		logger.Error("problem initialising blog", slog.Any("err", err))
-		return
+		return nil, fmt.Errorf("problem initialising blog: %w", err)
 	}
-	defer b.Close()
 
-	mux := http.NewServeMux()
+	return &site{
+		conf:                conf,
+		baseURL:             baseURL,
+		blog:                b,
+		fw:                  fw,
+		hub:                 websubhub,
+		idempotencyStore:    idempotencyStore,
+		micropubSyndicateTo: micropubSyndicateTo,
+	}, nil
+}
+
+// destinationsFor lists the sites that can be posted to from the micropub
+// endpoint of s, these are the sites that share the same me.
+func destinationsFor(s *site, sites []*site) []micropub.Destination {
+	var destinations []micropub.Destination
+
+	for _, other := range sites {
+		if other.conf.Me == s.conf.Me {
+			destinations = append(destinations, micropub.Destination{
+				UID:   other.baseURL.String(),
+				Name:  other.conf.Context.Name,
+				DB:    other.blog,
+				Media: other.fw,
+			})
+		}
+	}
 
-	mux.Handle("/", b.Handler())
+	if len(destinations) < 2 {
+		return nil
+	}
 
-	mux.Handle("/public/",
-		http.StripPrefix("/public/",
-			http.FileServer(
-				http.Dir(filepath.Join(*webPath, "static")))))
+	return destinations
+}
+
+func (s *site) handler(static http.Handler, destinations []micropub.Destination) http.Handler {
+	mediaEndpointURL, _ := url.Parse("-/media")
+
+	mux := http.NewServeMux()
+
+	mux.Handle("/", s.blog.Handler())
+	mux.Handle("/public/", static)
 
 	mux.Handle("/-/micropub", micropub.Endpoint(
-		b,
-		conf.Me,
-		baseURL.ResolveReference(mediaEndpointURL).String(),
-		micropubSyndicateTo,
-		fw))
-	mux.Handle("/-/webmention", webmention.Endpoint(b))
-	mux.Handle("/-/media", auth.Only(conf.Me, media.Endpoint(fw, auth.HasScope)))
-	mux.Handle("/-/hub", websubhub)
-
-	serve.Serve(*port, *socket,
-		http.StripPrefix(strings.TrimSuffix(baseURL.Path, "/"), mux))
+		s.blog,
+		s.conf.Me,
+		s.baseURL.ResolveReference(mediaEndpointURL).String(),
+		s.micropubSyndicateTo,
+		s.fw,
+		s.idempotencyStore,
+		destinations))
+	mux.Handle("/-/webmention", webmention.Endpoint(s.blog))
+	mux.Handle("/-/media", auth.Only(s.conf.Me, media.Endpoint(s.fw, auth.HasScope)))
+	mux.Handle("/-/hub", s.hub)
+
+	return http.StripPrefix(strings.TrimSuffix(s.baseURL.Path, "/"), mux)
 }
@@ -0,0 +1,50 @@
+package micropub
+
+import (
+	"strings"
+
+	"hawx.me/code/tally-ho/media"
+)
+
+// A Destination is a site that entries can be created on. When more than one
+// is given to Endpoint a client can choose which to use with mp-destination.
+//
+// See https://micropub.spec.indieweb.org/#destination.
+type Destination struct {
+	// UID identifies the destination, it should be the base URL of the site so
+	// that requests for existing entries can be routed to it.
+	UID  string `json:"uid"`
+	Name string `json:"name"`
+	DB   DB     `json:"-"`
+	// Media writes the files uploaded with entries for the destination, if not
+	// given they are written as for any other entry.
+	Media media.FileWriter `json:"-"`
+}
+
+// destinationByUID returns the destination that has the uid.
+func destinationByUID(destinations []Destination, uid string) (Destination, bool) {
+	for _, destination := range destinations {
+		if destination.UID == uid {
+			return destination, true
+		}
+	}
+
+	return Destination{}, false
+}
+
+// destinationForURL returns the destination that an existing entry, at url,
+// belongs to.
+func destinationForURL(destinations []Destination, url string) (Destination, bool) {
+	var (
+		found Destination
+		ok    bool
+	)
+
+	for _, destination := range destinations {
+		if strings.HasPrefix(url, destination.UID) && len(destination.UID) > len(found.UID) {
+			found, ok = destination, true
+		}
+	}
+
+	return found, ok
+}
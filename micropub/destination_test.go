@@ -0,0 +1,255 @@
+package micropub
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"hawx.me/code/assert"
+)
+
+type fakeDB struct {
+	*fakePostDB
+	*fakeGetDB
+}
+
+func (b fakeDB) Entry(url string) (map[string][]interface{}, error) {
+	return b.fakeGetDB.Entry(url)
+}
+
+func fakeDestinations() ([]Destination, fakeDB, fakeDB) {
+	blog := fakeDB{
+		fakePostDB: &fakePostDB{},
+		fakeGetDB: &fakeGetDB{
+			entries: map[string]map[string][]interface{}{
+				"https://example.com/blog/p/1": {"name": {"a blog post"}},
+			},
+			categories: []string{"blog"},
+		},
+	}
+	notes := fakeDB{
+		fakePostDB: &fakePostDB{},
+		fakeGetDB: &fakeGetDB{
+			entries: map[string]map[string][]interface{}{
+				"https://example.com/notes/p/1": {"name": {"a note"}},
+			},
+			categories: []string{"notes"},
+		},
+	}
+
+	return []Destination{
+		{UID: "https://example.com/blog/", Name: "Blog", DB: blog},
+		{UID: "https://example.com/notes/", Name: "Notes", DB: notes},
+	}, blog, notes
+}
+
+func TestDestinationForURL(t *testing.T) {
+	assert := assert.New(t)
+
+	destinations := []Destination{
+		{UID: "https://example.com/"},
+		{UID: "https://example.com/notes/"},
+	}
+
+	destination, ok := destinationForURL(destinations, "https://example.com/notes/p/1")
+	assert.True(ok)
+	assert.Equal("https://example.com/notes/", destination.UID)
+
+	destination, ok = destinationForURL(destinations, "https://example.com/p/1")
+	assert.True(ok)
+	assert.Equal("https://example.com/", destination.UID)
+
+	_, ok = destinationForURL(destinations, "https://other.example.com/p/1")
+	assert.False(ok)
+}
+
+func TestConfigurationDestination(t *testing.T) {
+	assert := assert.New(t)
+
+	destinations, _, _ := fakeDestinations()
+	handler := getHandler(nil, "", nil, destinations)
+
+	req := httptest.NewRequest("GET", "http://localhost/?q=config", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	resp := w.Result()
+
+	assert.Equal(http.StatusOK, resp.StatusCode)
+
+	var v struct {
+		Destination []struct {
+			UID  string `json:"uid"`
+			Name string `json:"name"`
+		} `json:"destination"`
+	}
+	json.NewDecoder(resp.Body).Decode(&v)
+
+	if assert.Len(v.Destination, 2) {
+		assert.Equal("https://example.com/blog/", v.Destination[0].UID)
+		assert.Equal("Blog", v.Destination[0].Name)
+		assert.Equal("https://example.com/notes/", v.Destination[1].UID)
+		assert.Equal("Notes", v.Destination[1].Name)
+	}
+}
+
+func TestConfigurationCategoryWithDestination(t *testing.T) {
+	assert := assert.New(t)
+
+	destinations, blog, _ := fakeDestinations()
+	handler := getHandler(blog, "", nil, destinations)
+
+	req := httptest.NewRequest("GET", "http://localhost/?q=category&mp-destination=https://example.com/notes/", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	resp := w.Result()
+
+	assert.Equal(http.StatusOK, resp.StatusCode)
+
+	var v struct {
+		Categories []string `json:"categories"`
+	}
+	json.NewDecoder(resp.Body).Decode(&v)
+	assert.Equal([]string{"notes"}, v.Categories)
+}
+
+func TestConfigurationSourceWithDestination(t *testing.T) {
+	assert := assert.New(t)
+
+	destinations, blog, _ := fakeDestinations()
+	handler := getHandler(blog, "", nil, destinations)
+
+	req := httptest.NewRequest("GET", "http://localhost/?q=source&url=https://example.com/notes/p/1", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	resp := w.Result()
+
+	assert.Equal(http.StatusOK, resp.StatusCode)
+
+	var v struct {
+		Properties map[string][]interface{} `json:"properties"`
+	}
+	json.NewDecoder(resp.Body).Decode(&v)
+	assert.Equal("a note", v.Properties["name"][0])
+}
+
+func TestPostEntryWithDestination(t *testing.T) {
+	testCases := map[string]*http.Request{
+		"url-encoded-form": newFormRequest(url.Values{
+			"h":              {"entry"},
+			"content":        {"This is a test"},
+			"mp-destination": {"https://example.com/notes/"},
+		}),
+		"json": newJSONRequest(`{
+  "type": ["h-entry"],
+  "properties": {
+    "content": ["This is a test"],
+    "mp-destination": ["https://example.com/notes/"]
+  }
+}`),
+	}
+
+	for name, req := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			destinations, blog, notes := fakeDestinations()
+			handler := withScope("create", postHandler(blog, nil, &fakeIdempotencyStore{}, destinations))
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			resp := w.Result()
+
+			assert.Equal(http.StatusCreated, resp.StatusCode)
+			assert.Len(blog.datas, 0)
+
+			if assert.Len(notes.datas, 1) {
+				assert.Equal("This is a test", notes.datas[0]["content"][0])
+
+				_, ok := notes.datas[0]["mp-destination"]
+				assert.False(ok)
+			}
+		})
+	}
+}
+
+func TestPostEntryWithUnknownDestination(t *testing.T) {
+	assert := assert.New(t)
+
+	destinations, blog, notes := fakeDestinations()
+	handler := withScope("create", postHandler(blog, nil, &fakeIdempotencyStore{}, destinations))
+
+	req := newFormRequest(url.Values{
+		"h":              {"entry"},
+		"content":        {"This is a test"},
+		"mp-destination": {"https://example.com/what/"},
+	})
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	resp := w.Result()
+
+	assert.Equal(http.StatusBadRequest, resp.StatusCode)
+	assert.Len(blog.datas, 0)
+	assert.Len(notes.datas, 0)
+}
+
+func TestPostEntryWithDestinationMedia(t *testing.T) {
+	testCases := map[string]struct {
+		destination string
+		status      int
+		written     []string
+	}{
+		"chosen":  {"https://example.com/notes/", http.StatusCreated, []string{"this is an image"}},
+		"unknown": {"https://example.com/what/", http.StatusBadRequest, nil},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			destinations, blog, notes := fakeDestinations()
+			blogFw, notesFw := &fakeFileWriter{}, &fakeFileWriter{}
+			destinations[0].Media = blogFw
+			destinations[1].Media = notesFw
+
+			handler := withScope("create", postHandler(blog, blogFw, &fakeIdempotencyStore{}, destinations))
+
+			req := newMultipartRequest(url.Values{
+				"h":              {"entry"},
+				"mp-destination": {tc.destination},
+			}, []multipartFile{{"photo", "whatever.png", "this is an image"}})
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			resp := w.Result()
+
+			assert.Equal(tc.status, resp.StatusCode)
+			assert.Len(blogFw.data, 0)
+			assert.Equal(tc.written, notesFw.data)
+			assert.Len(blog.datas, 0)
+			assert.Len(notes.datas, len(tc.written))
+		})
+	}
+}
+
+func TestDeleteEntryWithDestination(t *testing.T) {
+	assert := assert.New(t)
+
+	destinations, blog, notes := fakeDestinations()
+	handler := withScope("delete", postHandler(blog, nil, &fakeIdempotencyStore{}, destinations))
+
+	req := newFormRequest(url.Values{
+		"action": {"delete"},
+		"url":    {"https://example.com/notes/p/1"},
+	})
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	resp := w.Result()
+
+	assert.Equal(http.StatusNoContent, resp.StatusCode)
+	assert.Len(blog.deleted, 0)
+	assert.Equal([]string{"https://example.com/notes/p/1"}, notes.deleted)
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 
 	"hawx.me/code/mux"
+	"hawx.me/code/numbersix"
 	"hawx.me/code/tally-ho/auth"
 	"hawx.me/code/tally-ho/media"
 )
@@ -17,19 +18,29 @@ type DB interface {
 	Update(url string, replace, add, delete map[string][]interface{}, deleteAlls []string) error
 	Delete(url string) error
 	Undelete(url string) error
+	Restore(url string, version int) error
+	Purge(url string) error
+	Categories() ([]string, error)
+	OwnedPage(kind, before, after string, n int) (groups []numbersix.Group, newer, older string, err error)
 }
 
 // Endpoint returns a http.Handler exposing micropub. Only tokens issued for
-// 'me' are allowed access to post or retrieve configuration.
+// 'me' are allowed access to post or retrieve configuration. Creates are
+// recorded in store so that repeated requests do not make duplicate entries.
+//
+// Requests use db, unless destinations are given and the request chooses one
+// with mp-destination, or is for an entry on one of them.
 func Endpoint(
 	db DB,
 	me string,
 	mediaUploadURL string,
 	syndicateTo []SyndicateTo,
 	fw media.FileWriter,
+	store IdempotencyStore,
+	destinations []Destination,
 ) http.Handler {
 	return auth.Only(me, mux.Method{
-		"POST": postHandler(db, fw),
-		"GET":  getHandler(db, mediaUploadURL, syndicateTo),
+		"POST": postHandler(db, fw, store, destinations),
+		"GET":  getHandler(db, mediaUploadURL, syndicateTo, destinations),
 	})
 }
@@ -2,33 +2,52 @@ package micropub
 
 import (
 	"encoding/json"
+	"errors"
+	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
+
+	"hawx.me/code/numbersix"
+	"hawx.me/code/tally-ho/auth"
+	"hawx.me/code/tally-ho/blog"
+	"hawx.me/code/tally-ho/internal/posttype"
 )
 
 type getDB interface {
 	Entry(url string) (data map[string][]interface{}, err error)
+	Categories() ([]string, error)
+	OwnedPage(kind, before, after string, n int) (groups []numbersix.Group, newer, older string, err error)
 }
 
 func getHandler(
 	db getDB,
 	mediaURL string,
 	syndicateTo []SyndicateTo,
+	destinations []Destination,
 ) http.HandlerFunc {
-	configHandler := configHandler(mediaURL, syndicateTo)
-	sourceHandler := sourceHandler(db)
+	configHandler := configHandler(mediaURL, syndicateTo, destinations)
+	categoryHandler := categoryHandler(db, destinations)
+	sourceHandler := sourceHandler(db, destinations)
 	syndicationHandler := syndicationHandler(syndicateTo)
 	mediaEndpointHandler := mediaEndpointHandler(mediaURL)
 
 	return func(w http.ResponseWriter, r *http.Request) {
 		switch r.FormValue("q") {
+		case "category":
+			categoryHandler.ServeHTTP(w, r)
 		case "config":
 			configHandler.ServeHTTP(w, r)
 		case "media-endpoint":
 			mediaEndpointHandler.ServeHTTP(w, r)
+		case "post-types":
+			postTypesHandler(w, r)
 		case "source":
 			sourceHandler.ServeHTTP(w, r)
 		case "syndicate-to":
 			syndicationHandler.ServeHTTP(w, r)
+		default:
+			auth.Error(w, http.StatusBadRequest, "invalid_request", "q must be one of the values listed by q=config")
 		}
 	}
 }
@@ -38,26 +57,60 @@ type SyndicateTo struct {
 	Name string `json:"name"`
 }
 
-func configHandler(mediaURL string, syndicateTo []SyndicateTo) http.HandlerFunc {
+// chooseDB returns the db to use for a query, which is db unless a
+// mp-destination parameter is given.
+func chooseDB(w http.ResponseWriter, r *http.Request, db getDB, destinations []Destination) (getDB, bool) {
+	uid := r.FormValue("mp-destination")
+	if uid == "" {
+		return db, true
+	}
+
+	destination, ok := destinationByUID(destinations, uid)
+	if !ok {
+		auth.Error(w, http.StatusBadRequest, "invalid_request", "mp-destination must be one of the values listed by q=config")
+		return nil, false
+	}
+
+	return destination.DB, true
+}
+
+func configHandler(mediaURL string, syndicateTo []SyndicateTo, destinations []Destination) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(struct {
-			Q             []string      `json:"q"`
-			MediaEndpoint string        `json:"media-endpoint"`
-			SyndicateTo   []SyndicateTo `json:"syndicate-to"`
+			Q             []string        `json:"q"`
+			MediaEndpoint string          `json:"media-endpoint"`
+			SyndicateTo   []SyndicateTo   `json:"syndicate-to"`
+			Visibility    []string        `json:"visibility"`
+			PostTypes     []posttype.Type `json:"post-types"`
+			Destination   []Destination   `json:"destination,omitempty"`
 		}{
 			Q: []string{
+				"category",
 				"config",
 				"media-endpoint",
+				"post-types",
 				"source",
 				"syndicate-to",
 			},
 			MediaEndpoint: mediaURL,
 			SyndicateTo:   syndicateTo,
+			Visibility:    []string{"public", "unlisted", "private"},
+			PostTypes:     posttype.Types,
+			Destination:   destinations,
 		})
 	}
 }
 
+func postTypesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		PostTypes []posttype.Type `json:"post-types"`
+	}{
+		PostTypes: posttype.Types,
+	})
+}
+
 func mediaEndpointHandler(mediaURL string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
@@ -69,7 +122,39 @@ func mediaEndpointHandler(mediaURL string) http.HandlerFunc {
 	}
 }
 
-func sourceHandler(db getDB) http.HandlerFunc {
+func categoryHandler(db getDB, destinations []Destination) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db, ok := chooseDB(w, r, db, destinations)
+		if !ok {
+			return
+		}
+
+		categories, err := db.Categories()
+		if err != nil {
+			auth.Error(w, http.StatusInternalServerError, "server_error", "could not list categories")
+			return
+		}
+
+		if filter := strings.ToLower(r.FormValue("filter")); filter != "" {
+			filtered := []string{}
+			for _, category := range categories {
+				if strings.HasPrefix(strings.ToLower(category), filter) {
+					filtered = append(filtered, category)
+				}
+			}
+			categories = filtered
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(struct {
+			Categories []string `json:"categories"`
+		}{
+			Categories: categories,
+		})
+	}
+}
+
+func sourceHandler(db getDB, destinations []Destination) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		url := r.FormValue("url")
 		properties := r.Form["properties[]"]
@@ -80,23 +165,91 @@ func sourceHandler(db getDB) http.HandlerFunc {
 			}
 		}
 
+		if url == "" {
+			if db, ok := chooseDB(w, r, db, destinations); ok {
+				sourceList(w, r, db, properties)
+			}
+			return
+		}
+
+		db := db
+		if destination, ok := destinationForURL(destinations, url); ok {
+			db = destination.DB
+		}
+
 		obj, err := db.Entry(url)
+		if errors.Is(err, blog.ErrNotFound) {
+			auth.Error(w, http.StatusBadRequest, "invalid_request", "no entry exists for "+url)
+			return
+		}
 		if err != nil {
-			http.Error(w, "not found", http.StatusNotFound)
+			slog.Error("entry for source", slog.String("url", url), slog.Any("err", err))
+			auth.Error(w, http.StatusInternalServerError, "server_error", "the entry could not be read")
 			return
 		}
 
-		if len(properties) > 0 {
-			for key := range obj {
-				if !contains(key, properties) {
-					delete(obj, key)
-				}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(formToJSON(onlyProperties(obj, properties)))
+	}
+}
+
+// sourceLimit is the most posts that can be returned by a source list.
+const sourceLimit = 25
+
+type sourcePaging struct {
+	After  string `json:"after,omitempty"`
+	Before string `json:"before,omitempty"`
+}
+
+// sourceList responds with a page of recent posts. The 'after' cursor gives
+// the posts older than it, and the 'before' cursor the posts newer than it.
+// Cursors are given by the blog, and are only given when there are posts past
+// them.
+func sourceList(w http.ResponseWriter, r *http.Request, db getDB, properties []string) {
+	limit := sourceLimit
+	if l, err := strconv.Atoi(r.FormValue("limit")); err == nil && l > 0 && l < sourceLimit {
+		limit = l
+	}
+
+	// the blog pages from newest to oldest, so its cursors are the other way
+	// around
+	posts, newer, older, err := db.OwnedPage(r.FormValue("post-type"), r.FormValue("after"), r.FormValue("before"), limit)
+	if errors.Is(err, blog.ErrInvalidCursor) {
+		auth.Error(w, http.StatusBadRequest, "invalid_request", "before and after must be cursors given by a previous list")
+		return
+	}
+	if err != nil {
+		auth.Error(w, http.StatusInternalServerError, "server_error", "could not list posts")
+		return
+	}
+
+	items := make([]jsonMicroformat, len(posts))
+	for i, post := range posts {
+		items[i] = formToJSON(onlyProperties(post.Properties, properties))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Items  []jsonMicroformat `json:"items"`
+		Paging sourcePaging      `json:"paging"`
+	}{
+		Items:  items,
+		Paging: sourcePaging{After: older, Before: newer},
+	})
+}
+
+// onlyProperties removes all keys from obj that are not listed, unless the list
+// is empty.
+func onlyProperties(obj map[string][]any, properties []string) map[string][]any {
+	if len(properties) > 0 {
+		for key := range obj {
+			if !contains(key, properties) {
+				delete(obj, key)
 			}
 		}
-
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(formToJSON(obj))
 	}
+
+	return obj
 }
 
 type syndicationTarget struct {
@@ -8,18 +8,81 @@ import (
 	"testing"
 
 	"hawx.me/code/assert"
+	"hawx.me/code/numbersix"
+	"hawx.me/code/tally-ho/blog"
+	"hawx.me/code/tally-ho/internal/posttype"
 )
 
 type fakeGetDB struct {
-	entries map[string]map[string][]interface{}
+	entries    map[string]map[string][]interface{}
+	categories []string
+	// posts are expected to be ordered newest first
+	posts []numbersix.Group
+	err   error
 }
 
 func (b *fakeGetDB) Entry(url string) (map[string][]interface{}, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
 	if entry, ok := b.entries[url]; ok {
 		return entry, nil
 	}
 
-	return nil, errors.New("nope")
+	return nil, blog.ErrNotFound
+}
+
+func (b *fakeGetDB) Categories() ([]string, error) {
+	return b.categories, nil
+}
+
+// OwnedPage uses the subjects of posts as cursors.
+func (b *fakeGetDB) OwnedPage(kind, before, after string, n int) ([]numbersix.Group, string, string, error) {
+	var posts []numbersix.Group
+	for _, post := range b.posts {
+		if kind == "" || post.Properties["hx-kind"][0] == kind {
+			posts = append(posts, post)
+		}
+	}
+
+	indexOf := func(cursor string) int {
+		for i, post := range posts {
+			if post.Subject == cursor {
+				return i
+			}
+		}
+		return -1
+	}
+
+	start, end := 0, min(n, len(posts))
+	if after != "" {
+		i := indexOf(after)
+		if i < 0 {
+			return nil, "", "", blog.ErrInvalidCursor
+		}
+		start, end = max(0, i-n), i
+	} else if before != "" {
+		i := indexOf(before)
+		if i < 0 {
+			return nil, "", "", blog.ErrInvalidCursor
+		}
+		start, end = i+1, min(i+1+n, len(posts))
+	}
+
+	page := posts[start:end]
+	if len(page) == 0 {
+		return page, "", "", nil
+	}
+
+	var newer, older string
+	if start > 0 && (before != "" || after != "") {
+		newer = page[0].Subject
+	}
+	if end < len(posts) {
+		older = page[len(page)-1].Subject
+	}
+
+	return page, newer, older, nil
 }
 
 func fakeSyndicators() []SyndicateTo {
@@ -31,7 +94,7 @@ func fakeSyndicators() []SyndicateTo {
 func TestConfigurationConfig(t *testing.T) {
 	assert := assert.New(t)
 
-	handler := getHandler(nil, "http://media.example.com/", fakeSyndicators())
+	handler := getHandler(nil, "http://media.example.com/", fakeSyndicators(), nil)
 
 	req := httptest.NewRequest("GET", "http://localhost/?q=config", nil)
 
@@ -50,12 +113,19 @@ func TestConfigurationConfig(t *testing.T) {
 			UID  string `json:"uid"`
 			Name string `json:"name"`
 		} `json:"syndicate-to"`
+		Visibility []string `json:"visibility"`
+		PostTypes  []struct {
+			Type string `json:"type"`
+			Name string `json:"name"`
+		} `json:"post-types"`
 	}
 	json.NewDecoder(resp.Body).Decode(&v)
 
 	assert.Equal("http://media.example.com/", v.MediaEndpoint)
+	assert.Equal([]string{"public", "unlisted", "private"}, v.Visibility)
+	assert.Len(v.PostTypes, len(posttype.Types))
 
-	assert.Equal([]string{"config", "media-endpoint", "source", "syndicate-to"}, v.Q)
+	assert.Equal([]string{"category", "config", "media-endpoint", "post-types", "source", "syndicate-to"}, v.Q)
 
 	if assert.Len(v.SyndicateTo, 1) {
 		assert.Equal("https://fake/", v.SyndicateTo[0].UID)
@@ -63,6 +133,93 @@ func TestConfigurationConfig(t *testing.T) {
 	}
 }
 
+func TestConfigurationPostTypes(t *testing.T) {
+	assert := assert.New(t)
+
+	handler := getHandler(nil, "", nil, nil)
+
+	req := httptest.NewRequest("GET", "http://localhost/?q=post-types", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	resp := w.Result()
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	assert.Equal("application/json", resp.Header.Get("Content-Type"))
+
+	var v struct {
+		PostTypes []struct {
+			Type               string   `json:"type"`
+			Name               string   `json:"name"`
+			H                  string   `json:"h"`
+			Properties         []string `json:"properties"`
+			RequiredProperties []string `json:"required-properties"`
+		} `json:"post-types"`
+	}
+	json.NewDecoder(resp.Body).Decode(&v)
+
+	if assert.Len(v.PostTypes, len(posttype.Types)) {
+		like := v.PostTypes[5]
+		assert.Equal("like", like.Type)
+		assert.Equal("Like", like.Name)
+		assert.Equal("entry", like.H)
+		assert.Equal([]string{"like-of"}, like.Properties)
+		assert.Equal([]string{"like-of"}, like.RequiredProperties)
+	}
+}
+
+func TestConfigurationCategory(t *testing.T) {
+	blog := &fakeGetDB{
+		categories: []string{"go", "indieweb", "golang", "micropub"},
+	}
+
+	testCases := map[string]struct {
+		query    string
+		expected []string
+	}{
+		"all": {
+			query:    "?q=category",
+			expected: []string{"go", "indieweb", "golang", "micropub"},
+		},
+		"filtered": {
+			query:    "?q=category&filter=go",
+			expected: []string{"go", "golang"},
+		},
+		"filtered ignoring case": {
+			query:    "?q=category&filter=MICRO",
+			expected: []string{"micropub"},
+		},
+		"filtered to nothing": {
+			query:    "?q=category&filter=what",
+			expected: []string{},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			handler := getHandler(blog, "", fakeSyndicators(), nil)
+
+			req := httptest.NewRequest("GET", "http://localhost/"+tc.query, nil)
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			resp := w.Result()
+
+			assert.Equal(http.StatusOK, resp.StatusCode)
+			assert.Equal("application/json", resp.Header.Get("Content-Type"))
+
+			var v struct {
+				Categories []string `json:"categories"`
+			}
+			json.NewDecoder(resp.Body).Decode(&v)
+
+			assert.Equal(tc.expected, v.Categories)
+		})
+	}
+}
+
 func TestConfigurationSource(t *testing.T) {
 	assert := assert.New(t)
 
@@ -71,11 +228,12 @@ func TestConfigurationSource(t *testing.T) {
 			"https://example.com/weblog/p/1": {
 				"h":     {"entry"},
 				"title": {"Cool post"},
+				"photo": {map[string]interface{}{"value": "https://example.com/a.jpg", "alt": "a cat"}},
 			},
 		},
 	}
 
-	handler := getHandler(blog, "", fakeSyndicators())
+	handler := getHandler(blog, "", fakeSyndicators(), nil)
 
 	req := httptest.NewRequest("GET", "http://localhost/?q=source&url=https://example.com/weblog/p/1", nil)
 
@@ -95,6 +253,40 @@ func TestConfigurationSource(t *testing.T) {
 
 	assert.Equal("h-entry", v.Type[0])
 	assert.Equal("Cool post", v.Properties["title"][0])
+	assert.Equal(map[string]interface{}{"value": "https://example.com/a.jpg", "alt": "a cat"}, v.Properties["photo"][0])
+}
+
+func TestConfigurationSourceWhenEntryCanNotBeRead(t *testing.T) {
+	testCases := map[string]struct {
+		err    error
+		status int
+		code   string
+	}{
+		"missing": {blog.ErrNotFound, http.StatusBadRequest, "invalid_request"},
+		"broken":  {errors.New("database is locked"), http.StatusInternalServerError, "server_error"},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			handler := getHandler(&fakeGetDB{err: tc.err}, "", fakeSyndicators(), nil)
+
+			req := httptest.NewRequest("GET", "http://localhost/?q=source&url=https://example.com/blog/p/1", nil)
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			resp := w.Result()
+			assert.Equal(tc.status, resp.StatusCode)
+
+			var v struct {
+				Error string `json:"error"`
+			}
+			json.NewDecoder(resp.Body).Decode(&v)
+			assert.Equal(tc.code, v.Error)
+		})
+	}
 }
 
 func TestConfigurationSourceWithProperties(t *testing.T) {
@@ -109,7 +301,7 @@ func TestConfigurationSourceWithProperties(t *testing.T) {
 		},
 	}
 
-	handler := getHandler(blog, "", fakeSyndicators())
+	handler := getHandler(blog, "", fakeSyndicators(), nil)
 
 	req := httptest.NewRequest("GET", "http://localhost/?q=source&properties=title&url=https://example.com/weblog/p/1", nil)
 
@@ -145,7 +337,7 @@ func TestConfigurationSourceWithManyProperties(t *testing.T) {
 		},
 	}
 
-	handler := getHandler(blog, "", fakeSyndicators())
+	handler := getHandler(blog, "", fakeSyndicators(), nil)
 
 	req := httptest.NewRequest("GET", "http://localhost/?q=source&properties[]=title&properties[]=categories&url=https://example.com/weblog/p/1", nil)
 
@@ -170,10 +362,167 @@ func TestConfigurationSourceWithManyProperties(t *testing.T) {
 	assert.Equal("test", v.Properties["categories"][1])
 }
 
+func TestConfigurationSourceList(t *testing.T) {
+	post := func(published, kind, name string) numbersix.Group {
+		return numbersix.Group{
+			Subject: name,
+			Properties: map[string][]interface{}{
+				"h":         {"entry"},
+				"published": {published},
+				"hx-kind":   {kind},
+				"name":      {name},
+			},
+		}
+	}
+
+	testCases := map[string]struct {
+		query         string
+		names         []string
+		after, before string
+	}{
+		"latest": {
+			query: "?q=source",
+			names: []string{"four", "three", "two", "one"},
+		},
+		"limited": {
+			query: "?q=source&limit=2",
+			names: []string{"four", "three"},
+			after: "three",
+		},
+		"after": {
+			query:  "?q=source&limit=2&after=three",
+			names:  []string{"two", "one"},
+			before: "two",
+		},
+		"before": {
+			query: "?q=source&limit=2&before=two",
+			names: []string{"four", "three"},
+			after: "three",
+		},
+		"post-type": {
+			query: "?q=source&post-type=article",
+			names: []string{"three", "one"},
+		},
+		"post-type-limited": {
+			query: "?q=source&post-type=article&limit=1",
+			names: []string{"three"},
+			after: "three",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			blog := &fakeGetDB{
+				posts: []numbersix.Group{
+					post("2020-01-04T12:00:00Z", "note", "four"),
+					post("2020-01-03T12:00:00Z", "article", "three"),
+					post("2020-01-02T12:00:00Z", "note", "two"),
+					post("2020-01-01T12:00:00Z", "article", "one"),
+				},
+			}
+
+			handler := getHandler(blog, "", fakeSyndicators(), nil)
+
+			req := httptest.NewRequest("GET", "http://localhost/"+tc.query, nil)
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			resp := w.Result()
+
+			assert.Equal(http.StatusOK, resp.StatusCode)
+			assert.Equal("application/json", resp.Header.Get("Content-Type"))
+
+			var v struct {
+				Items []struct {
+					Type       []string
+					Properties map[string][]interface{}
+				}
+				Paging struct {
+					After  string
+					Before string
+				}
+			}
+			json.NewDecoder(resp.Body).Decode(&v)
+
+			var names []string
+			for _, item := range v.Items {
+				assert.Equal("h-entry", item.Type[0])
+				names = append(names, item.Properties["name"][0].(string))
+			}
+
+			assert.Equal(tc.names, names)
+			assert.Equal(tc.after, v.Paging.After)
+			assert.Equal(tc.before, v.Paging.Before)
+		})
+	}
+}
+
+func TestConfigurationSourceListWithUnknownCursor(t *testing.T) {
+	assert := assert.New(t)
+
+	handler := getHandler(&fakeGetDB{}, "", fakeSyndicators(), nil)
+
+	req := httptest.NewRequest("GET", "http://localhost/?q=source&after=2020-01-03T12:00:00Z", nil)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	resp := w.Result()
+
+	assert.Equal(http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestConfigurationSourceListWithProperties(t *testing.T) {
+	assert := assert.New(t)
+
+	blog := &fakeGetDB{
+		posts: []numbersix.Group{
+			{
+				Subject: "1",
+				Properties: map[string][]interface{}{
+					"h":         {"entry"},
+					"published": {"2020-01-01T12:00:00Z"},
+					"name":      {"Cool post"},
+					"content":   {"goodness"},
+				},
+			},
+		},
+	}
+
+	handler := getHandler(blog, "", fakeSyndicators(), nil)
+
+	req := httptest.NewRequest("GET", "http://localhost/?q=source&properties[]=name&properties[]=published", nil)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	resp := w.Result()
+
+	assert.Equal(http.StatusOK, resp.StatusCode)
+
+	var v struct {
+		Items []struct {
+			Type       []string
+			Properties map[string][]interface{}
+		}
+	}
+	json.NewDecoder(resp.Body).Decode(&v)
+
+	if assert.Len(v.Items, 1) {
+		assert.Len(v.Items[0].Type, 0)
+		assert.Len(v.Items[0].Properties, 2)
+		assert.Equal("Cool post", v.Items[0].Properties["name"][0])
+		assert.Equal("2020-01-01T12:00:00Z", v.Items[0].Properties["published"][0])
+	}
+}
+
 func TestConfigurationSyndicationTarget(t *testing.T) {
 	assert := assert.New(t)
 
-	handler := getHandler(nil, "http://media.example.com/", fakeSyndicators())
+	handler := getHandler(nil, "http://media.example.com/", fakeSyndicators(), nil)
 
 	req := httptest.NewRequest("GET", "http://localhost/?q=syndicate-to", nil)
 
@@ -202,7 +551,7 @@ func TestConfigurationSyndicationTarget(t *testing.T) {
 func TestConfigurationMediaEndpoint(t *testing.T) {
 	assert := assert.New(t)
 
-	handler := getHandler(nil, "http://media.example.com/", fakeSyndicators())
+	handler := getHandler(nil, "http://media.example.com/", fakeSyndicators(), nil)
 
 	req := httptest.NewRequest("GET", "http://localhost/?q=media-endpoint", nil)
 
@@ -0,0 +1,174 @@
+package micropub
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"sync"
+	"time"
+
+	"hawx.me/code/tally-ho/auth"
+)
+
+const (
+	// idempotencyKeyTTL is how long a create with an Idempotency-Key header is
+	// remembered.
+	idempotencyKeyTTL = 24 * time.Hour
+
+	// idempotencyHashTTL is how long a create without an Idempotency-Key header
+	// is remembered, this is shorter as a repeated body may be intentional.
+	idempotencyHashTTL = 10 * time.Minute
+)
+
+// IdempotencyStore records the locations of entries that have been created,
+// along with a hash of the request that created them.
+type IdempotencyStore interface {
+	Location(key string) (location, hash string, ok bool, err error)
+	Remember(key, hash, location string, expiresAt time.Time) error
+}
+
+// idempotent wraps a handler so that a request repeating a successful create
+// is answered with the original location, instead of creating another entry.
+//
+// Requests are matched using the Idempotency-Key header, or when that is not
+// given a hash of the client id and body. Multipart bodies are only matched by
+// the header, as a repeated upload of the same file is more likely intended.
+// A key that is reused for a different request is refused.
+func idempotent(store IdempotencyStore, next http.Handler) http.Handler {
+	var locks keyLocks
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, hash, ttl, err := idempotencyKey(r)
+		if err != nil {
+			auth.Error(w, http.StatusBadRequest, "invalid_request", "could not read request: "+err.Error())
+			return
+		}
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		defer locks.lock(key)()
+
+		location, createdHash, ok, err := store.Location(key)
+		if err != nil {
+			slog.Error("idempotency location", slog.Any("err", err))
+		}
+		if ok {
+			if createdHash != hash {
+				auth.Error(w, http.StatusUnprocessableEntity, "invalid_request", "Idempotency-Key has been used for a different request")
+				return
+			}
+			if !auth.HasScope(w, r, "create") {
+				return
+			}
+
+			slog.Info("repeated create", slog.String("url", location))
+			w.Header().Add("Location", location)
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+
+		if rec.status == http.StatusCreated {
+			if err := store.Remember(key, hash, w.Header().Get("Location"), time.Now().Add(ttl)); err != nil {
+				slog.Error("idempotency remember", slog.Any("err", err))
+			}
+		}
+	})
+}
+
+// idempotencyKey returns the key to match repeated requests with, a hash of the
+// request, and how long it should be remembered for. The body of r will be
+// replaced if it is read.
+func idempotencyKey(r *http.Request) (key, hash string, ttl time.Duration, err error) {
+	clientID := auth.ClientID(r)
+	idempotencyKey := r.Header.Get("Idempotency-Key")
+
+	_, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	boundary, isMultipart := params["boundary"]
+
+	if idempotencyKey == "" && isMultipart {
+		return "", "", 0, nil
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return "", "", 0, err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	// the boundary is chosen by the client for each request, so is left out to
+	// match a repeat of the same parts
+	if isMultipart {
+		body = bytes.ReplaceAll(body, []byte(boundary), nil)
+	}
+
+	h := sha256.New()
+	io.WriteString(h, clientID)
+	h.Write([]byte{0})
+	h.Write(body)
+	hash = hex.EncodeToString(h.Sum(nil))
+
+	if idempotencyKey != "" {
+		return "key " + clientID + " " + idempotencyKey, hash, idempotencyKeyTTL, nil
+	}
+
+	return "hash " + hash, hash, idempotencyHashTTL, nil
+}
+
+// keyLocks holds a lock for each key that is in use, so that requests with the
+// same key are handled one at a time without holding up any others.
+type keyLocks struct {
+	mu    sync.Mutex
+	locks map[string]*keyLock
+}
+
+type keyLock struct {
+	sync.Mutex
+	waiting int
+}
+
+// lock waits for key to be free, and returns a func to free it again.
+func (l *keyLocks) lock(key string) func() {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = map[string]*keyLock{}
+	}
+	kl, ok := l.locks[key]
+	if !ok {
+		kl = &keyLock{}
+		l.locks[key] = kl
+	}
+	kl.waiting++
+	l.mu.Unlock()
+
+	kl.Lock()
+
+	return func() {
+		kl.Unlock()
+
+		l.mu.Lock()
+		kl.waiting--
+		if kl.waiting == 0 {
+			delete(l.locks, key)
+		}
+		l.mu.Unlock()
+	}
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
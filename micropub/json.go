@@ -10,6 +10,7 @@ type jsonMicroformat struct {
 	Add        map[string][]any `json:"add,omitempty"`
 	Delete     any              `json:"delete,omitempty"`
 	Replace    map[string][]any `json:"replace,omitempty"`
+	Revision   int              `json:"revision,omitempty"`
 }
 
 func jsonToForm(v jsonMicroformat) map[string][]any {
@@ -24,7 +25,7 @@ func jsonToForm(v jsonMicroformat) map[string][]any {
 	}
 
 	for key, value := range v.Properties {
-		if reservedKey(key) || len(value) == 0 {
+		if key = propertyKey(key); reservedKey(key) || len(value) == 0 {
 			continue
 		}
 
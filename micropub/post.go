@@ -1,49 +1,95 @@
 package micropub
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"io"
 	"log/slog"
 	"mime"
 	"mime/multipart"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 
 	"hawx.me/code/mux"
 	"hawx.me/code/tally-ho/auth"
+	"hawx.me/code/tally-ho/blog"
+	"hawx.me/code/tally-ho/internal/mfutil"
 	"hawx.me/code/tally-ho/media"
 )
 
 type postDB interface {
+	Entry(url string) (map[string][]any, error)
 	Create(data map[string][]any) (string, error)
 	Update(url string, replace, add, delete map[string][]any, deleteAlls []string) error
 	Delete(url string) error
 	Undelete(url string) error
+	Restore(url string, version int) error
+	Purge(url string) error
 }
 
-func postHandler(db postDB, fw media.FileWriter) http.Handler {
+func postHandler(db postDB, fw media.FileWriter, store IdempotencyStore, destinations []Destination) http.Handler {
 	h := micropubPostHandler{
-		db: db,
-		fw: fw,
+		db:           db,
+		fw:           fw,
+		destinations: destinations,
 	}
 
-	return mux.ContentType{
+	return idempotent(store, mux.ContentType{
 		"application/json":                  http.HandlerFunc(h.handleJSON),
 		"application/x-www-form-urlencoded": http.HandlerFunc(h.handleForm),
 		"multipart/form-data":               http.HandlerFunc(h.handleMultiPart),
-	}
+	})
 }
 
 type micropubPostHandler struct {
-	db postDB
-	fw media.FileWriter
+	db           postDB
+	fw           media.FileWriter
+	destinations []Destination
+}
+
+// dbFor returns the db that contains the entry at url.
+func (h *micropubPostHandler) dbFor(url string) postDB {
+	if destination, ok := destinationForURL(h.destinations, url); ok {
+		return destination.DB
+	}
+
+	return h.db
+}
+
+// fwFor returns the writer for the media in a form. This is the writer of the
+// destination chosen with mp-destination, or of the destination that the entry
+// at url belongs to. If the chosen destination does not exist false is
+// returned.
+func (h *micropubPostHandler) fwFor(form url.Values) (media.FileWriter, bool) {
+	var (
+		destination Destination
+		ok          bool
+	)
+
+	if uid := form.Get("mp-destination"); uid != "" {
+		destination, ok = destinationByUID(h.destinations, uid)
+		if !ok {
+			return nil, false
+		}
+	} else {
+		destination, ok = destinationForURL(h.destinations, form.Get("url"))
+	}
+
+	if ok && destination.Media != nil {
+		return destination.Media, true
+	}
+
+	return h.fw, true
 }
 
 func (h *micropubPostHandler) handleJSON(w http.ResponseWriter, r *http.Request) {
 	v := jsonMicroformat{Properties: map[string][]any{}}
 
 	if err := json.NewDecoder(r.Body).Decode(&v); err != nil {
-		http.Error(w, "could not decode json request: "+err.Error(), http.StatusBadRequest)
+		auth.Error(w, http.StatusBadRequest, "invalid_request", "could not decode json request: "+err.Error())
 		return
 	}
 
@@ -52,7 +98,7 @@ func (h *micropubPostHandler) handleJSON(w http.ResponseWriter, r *http.Request)
 	if v.Action == "update" {
 		replace := map[string][]any{}
 		for key, value := range v.Replace {
-			if reservedKey(key) {
+			if key = propertyKey(key); reservedKey(key) {
 				continue
 			}
 
@@ -61,7 +107,7 @@ func (h *micropubPostHandler) handleJSON(w http.ResponseWriter, r *http.Request)
 
 		add := map[string][]any{}
 		for key, value := range v.Add {
-			if reservedKey(key) {
+			if key = propertyKey(key); reservedKey(key) {
 				continue
 			}
 
@@ -74,37 +120,28 @@ func (h *micropubPostHandler) handleJSON(w http.ResponseWriter, r *http.Request)
 		if ds, ok := v.Delete.([]any); ok {
 			for _, d := range ds {
 				if dd, ok := d.(string); ok {
-					deleteAlls = append(deleteAlls, dd)
+					deleteAlls = append(deleteAlls, propertyKey(dd))
 				} else {
-					http.Error(w, "could not decode json request: malformed delete", http.StatusBadRequest)
+					auth.Error(w, http.StatusBadRequest, "invalid_request", "could not decode json request: malformed delete")
 					return
 				}
 			}
 		} else if dm, ok := v.Delete.(map[string]any); ok {
 			for key, value := range dm {
-				if reservedKey(key) {
+				if key = propertyKey(key); reservedKey(key) {
 					continue
 				}
 
 				if vs, ok := value.([]any); ok {
 					delete[key] = vs
 				} else {
-					http.Error(w, "could not decode json request: malformed delete", http.StatusBadRequest)
+					auth.Error(w, http.StatusBadRequest, "invalid_request", "could not decode json request: malformed delete")
 					return
 				}
 			}
 		}
 
-		if !auth.HasScope(w, r, "update") {
-			return
-		}
-
-		if err := h.db.Update(v.URL, replace, add, delete, deleteAlls); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-
-		w.WriteHeader(http.StatusNoContent)
+		h.update(w, r, v.URL, replace, add, delete, deleteAlls)
 		return
 	}
 
@@ -118,61 +155,40 @@ func (h *micropubPostHandler) handleJSON(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	h.create(w, r, data)
-}
-
-func (h *micropubPostHandler) handleForm(w http.ResponseWriter, r *http.Request) {
-	if err := r.ParseForm(); err != nil {
-		http.Error(w, "could not parse form: "+err.Error(), http.StatusBadRequest)
-		return
-	}
-
-	if r.FormValue("action") == "delete" {
-		h.delete(w, r, r.FormValue("url"))
+	if v.Action == "restore" {
+		h.restore(w, r, v.URL, v.Revision)
 		return
 	}
 
-	if r.FormValue("action") == "undelete" {
-		h.undelete(w, r, r.FormValue("url"))
+	if v.Action == "purge" {
+		h.purge(w, r, v.URL)
 		return
 	}
 
-	data := map[string][]any{}
-
-	for key, values := range r.Form {
-		if reservedKey(key) {
-			continue
-		}
-
-		if strings.HasSuffix(key, "[]") {
-			key := key[:len(key)-2]
-			for _, value := range values {
-				if value != "" {
-					data[key] = append(data[key], value)
-				}
-			}
-		} else {
-			if values[0] != "" {
-				data[key] = []any{values[0]}
-			}
-		}
-	}
-
 	h.create(w, r, data)
 }
 
-func (h *micropubPostHandler) handleMultiPart(w http.ResponseWriter, r *http.Request) {
-	if !auth.HasScope(w, r, "create") {
+func (h *micropubPostHandler) handleForm(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		auth.Error(w, http.StatusBadRequest, "invalid_request", "could not parse form: "+err.Error())
 		return
 	}
 
+	h.handleValues(w, r, r.Form)
+}
+
+func (h *micropubPostHandler) handleMultiPart(w http.ResponseWriter, r *http.Request) {
 	_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
 	if err != nil {
-		slog.Error("micropub parse content type", slog.Any("err", err))
+		auth.Error(w, http.StatusBadRequest, "invalid_request", "could not parse content type: "+err.Error())
 		return
 	}
 
-	data := map[string][]any{}
+	form := url.Values{}
+	var (
+		medias []*mediaPart
+		photos photoParts
+	)
 	parts := multipart.NewReader(r.Body, params["boundary"])
 
 	for {
@@ -181,8 +197,7 @@ func (h *micropubPostHandler) handleMultiPart(w http.ResponseWriter, r *http.Req
 			break
 		}
 		if err != nil {
-			slog.Error("micropub read multi part", slog.Any("err", err))
-			http.Error(w, "", http.StatusInternalServerError)
+			auth.Error(w, http.StatusBadRequest, "invalid_request", "could not read multipart form: "+err.Error())
 			return
 		}
 
@@ -193,52 +208,297 @@ func (h *micropubPostHandler) handleMultiPart(w http.ResponseWriter, r *http.Req
 
 		key := p.FormName()
 
-		switch key {
-		case "photo", "video", "audio":
-			location, err := h.fw.WriteFile(ps["filename"], p.Header.Get("Content-Type"), p)
-			if err != nil {
-				slog.Error("micropub photo", slog.Any("err", err))
-				continue
+		slurp, err := io.ReadAll(p)
+		if err != nil {
+			auth.Error(w, http.StatusBadRequest, "invalid_request", "could not read multipart form: "+err.Error())
+			return
+		}
+
+		switch formKey(key) {
+		case "photo":
+			if isMediaKey(key) {
+				photos.add(&photoPart{media: &mediaPart{key, ps["filename"], p.Header.Get("Content-Type"), slurp}})
+			} else {
+				photos.add(&photoPart{value: string(slurp)})
 			}
+			continue
+		case "mp-photo-alt":
+			photos.addAlt(string(slurp))
+			continue
+		}
 
-			data[key] = []any{location}
+		if isMediaKey(key) {
+			medias = append(medias, &mediaPart{key, ps["filename"], p.Header.Get("Content-Type"), slurp})
+			continue
+		}
 
-		case "photo[]", "video[]", "audio[]":
-			location, err := h.fw.WriteFile(ps["filename"], p.Header.Get("Content-Type"), p)
-			if err != nil {
-				slog.Error("micropub-photo", slog.Any("err", err))
-				continue
+		form.Add(key, string(slurp))
+	}
+
+	// the action isn't known until the whole body has been read, so media can
+	// only be written once the token is known to allow it
+	if !auth.HasScope(w, r, actionScope(form.Get("action"))) {
+		return
+	}
+
+	fw, ok := h.fwFor(form)
+	if !ok {
+		auth.Error(w, http.StatusBadRequest, "invalid_request", "mp-destination must be one of the values listed by q=config")
+		return
+	}
+
+	write := func(m *mediaPart) (string, bool) {
+		location, err := fw.WriteFile(m.filename, m.contentType, bytes.NewReader(m.data))
+		if err != nil {
+			slog.Error("micropub media", slog.String("key", m.key), slog.Any("err", err))
+			auth.Error(w, http.StatusInternalServerError, "server_error", "could not write "+m.key)
+			return "", false
+		}
+
+		return location, true
+	}
+
+	for _, m := range medias {
+		location, ok := write(m)
+		if !ok {
+			return
+		}
+
+		form.Add(m.key, location)
+	}
+
+	var hasAlt bool
+	for _, photo := range photos.list {
+		if photo.media != nil {
+			location, ok := write(photo.media)
+			if !ok {
+				return
 			}
+			photo.value = location
+		}
 
-			key := key[:len(key)-2]
-			data[key] = append(data[key], location)
+		form.Add("photo[]", photo.value)
+		form.Add("mp-photo-alt[]", photo.alt)
+		hasAlt = hasAlt || photo.hasAlt
+	}
+	if !hasAlt {
+		form.Del("mp-photo-alt[]")
+	}
 
-		default:
-			if reservedKey(key) {
-				continue
+	h.handleValues(w, r, form)
+}
+
+type mediaPart struct {
+	key, filename, contentType string
+	data                       []byte
+}
+
+type photoPart struct {
+	value  string
+	media  *mediaPart
+	alt    string
+	hasAlt bool
+}
+
+// photoParts pairs the photos in a multipart body with their alt text. Alt
+// text describes the photo part given just before it, unless that photo
+// already has alt text, in which case it describes the next photo part.
+type photoParts struct {
+	list    []*photoPart
+	waiting []string
+}
+
+func (ps *photoParts) add(p *photoPart) {
+	if len(ps.waiting) > 0 {
+		p.alt, p.hasAlt = ps.waiting[0], true
+		ps.waiting = ps.waiting[1:]
+	}
+
+	ps.list = append(ps.list, p)
+}
+
+func (ps *photoParts) addAlt(alt string) {
+	if len(ps.list) > 0 {
+		if last := ps.list[len(ps.list)-1]; !last.hasAlt {
+			last.alt, last.hasAlt = alt, true
+			return
+		}
+	}
+
+	ps.waiting = append(ps.waiting, alt)
+}
+
+// formKey returns the property a key in a form to create an entry is for.
+func formKey(key string) string {
+	key = strings.TrimSuffix(key, "[]")
+	if alias, ok := formAliases[key]; ok {
+		return alias
+	}
+
+	return key
+}
+
+// actionScope returns the scope a token needs to perform the action.
+func actionScope(action string) string {
+	switch action {
+	case "delete", "undelete", "purge":
+		return "delete"
+	case "update", "restore":
+		return "update"
+	default:
+		return "create"
+	}
+}
+
+// handleValues handles a request that has been decoded to form values, either
+// from a url-encoded or multipart body.
+func (h *micropubPostHandler) handleValues(w http.ResponseWriter, r *http.Request, form url.Values) {
+	switch form.Get("action") {
+	case "delete":
+		h.delete(w, r, form.Get("url"))
+
+	case "undelete":
+		h.undelete(w, r, form.Get("url"))
+
+	case "update":
+		replace, add, delete, deleteAlls := formToUpdate(form)
+		h.update(w, r, form.Get("url"), replace, add, delete, deleteAlls)
+
+	case "restore":
+		revision, err := strconv.Atoi(form.Get("revision"))
+		if err != nil {
+			auth.Error(w, http.StatusBadRequest, "invalid_request", "revision must be a number")
+			return
+		}
+
+		h.restore(w, r, form.Get("url"), revision)
+
+	case "purge":
+		h.purge(w, r, form.Get("url"))
+
+	default:
+		h.create(w, r, formToData(form))
+	}
+}
+
+// isMediaKey returns true if the multipart field with the key should be
+// written using the media.FileWriter. This is the case for photo, video and
+// audio fields, whether for a create or within an update.
+func isMediaKey(key string) bool {
+	key = strings.TrimSuffix(key, "[]")
+
+	for _, op := range []string{"replace", "add"} {
+		if prop, ok := bracketed(key, op); ok {
+			key = prop
+			break
+		}
+	}
+
+	return key == "photo" || key == "video" || key == "audio"
+}
+
+// formAliases are keys that can be used in a form for another property.
+var formAliases = map[string]string{
+	"photo[value]": "photo",
+	"photo[alt]":   "mp-photo-alt",
+}
+
+// positionalKeys are matched to the values of another property by position, so
+// must keep their empty values to stay in line.
+var positionalKeys = map[string]bool{
+	"mp-photo-alt": true,
+}
+
+func formToData(form url.Values) map[string][]any {
+	data := map[string][]any{}
+
+	for key, values := range form {
+		if base, multiple := strings.CutSuffix(key, "[]"); base == "url" {
+			key = propertyKey(base)
+			if multiple {
+				key += "[]"
 			}
+		}
 
-			slurp, err := io.ReadAll(p)
-			if err != nil {
-				slog.Error("could not read", slog.Any("err", err))
-				return
+		if reservedKey(key) {
+			continue
+		}
+
+		if base, multiple := strings.CutSuffix(key, "[]"); formAliases[base] != "" {
+			key = formAliases[base]
+			if multiple {
+				key += "[]"
 			}
+		}
 
-			value := string(slurp)
-			if value == "" {
+		if strings.HasSuffix(key, "[]") {
+			key := key[:len(key)-2]
+			for _, value := range values {
+				if value != "" || positionalKeys[key] {
+					data[key] = append(data[key], value)
+				}
+			}
+		} else {
+			if values[0] != "" {
+				data[key] = []any{values[0]}
+			}
+		}
+	}
+
+	return data
+}
+
+// formToUpdate reads the changes for an update from form values. Properties
+// are given as replace[content][]=..., add[category][]=..., delete[]=category
+// to remove a property completely, or delete[category][]=... to remove a
+// single value.
+func formToUpdate(form url.Values) (replace, add, delete map[string][]any, deleteAlls []string) {
+	replace = map[string][]any{}
+	add = map[string][]any{}
+	delete = map[string][]any{}
+
+	for key, values := range form {
+		key = strings.TrimSuffix(key, "[]")
+
+		if key == "delete" {
+			for _, value := range values {
+				if value = propertyKey(value); value != "" && !reservedKey(value) {
+					deleteAlls = append(deleteAlls, value)
+				}
+			}
+			continue
+		}
+
+		for op, changes := range map[string]map[string][]any{
+			"replace": replace,
+			"add":     add,
+			"delete":  delete,
+		} {
+			prop, ok := bracketed(key, op)
+			if prop = propertyKey(prop); !ok || reservedKey(prop) {
 				continue
 			}
 
-			if strings.HasSuffix(key, "[]") {
-				key := key[:len(key)-2]
-				data[key] = append(data[key], value)
-			} else {
-				data[key] = []any{value}
+			for _, value := range values {
+				if value != "" {
+					changes[prop] = append(changes[prop], value)
+				}
 			}
 		}
 	}
 
-	h.create(w, r, data)
+	return
+}
+
+// bracketed returns the property from a key of the form op[prop].
+func bracketed(key, op string) (string, bool) {
+	if !strings.HasPrefix(key, op+"[") || !strings.HasSuffix(key, "]") {
+		return "", false
+	}
+
+	prop := key[len(op)+1 : len(key)-1]
+
+	return prop, prop != ""
 }
 
 func (h *micropubPostHandler) create(w http.ResponseWriter, r *http.Request, data map[string][]any) {
@@ -246,13 +506,31 @@ func (h *micropubPostHandler) create(w http.ResponseWriter, r *http.Request, dat
 		return
 	}
 
+	db := h.db
+	if uid, ok := mfutil.Get(data, "mp-destination").(string); ok {
+		destination, ok := destinationByUID(h.destinations, uid)
+		if !ok {
+			auth.Error(w, http.StatusBadRequest, "invalid_request", "mp-destination must be one of the values listed by q=config")
+			return
+		}
+
+		db = destination.DB
+		delete(data, "mp-destination")
+	}
+
+	if err := validate(data); err != nil {
+		auth.Error(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
 	if clientID := auth.ClientID(r); clientID != "" {
 		data["hx-client-id"] = []any{clientID}
 	}
 
-	location, err := h.db.Create(data)
+	location, err := db.Create(data)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		slog.Error("create", slog.Any("err", err))
+		auth.Error(w, http.StatusInternalServerError, "server_error", "the entry could not be created")
 		return
 	}
 
@@ -260,15 +538,46 @@ func (h *micropubPostHandler) create(w http.ResponseWriter, r *http.Request, dat
 	w.WriteHeader(http.StatusCreated)
 }
 
+func (h *micropubPostHandler) update(w http.ResponseWriter, r *http.Request, url string, replace, add, delete map[string][]any, deleteAlls []string) {
+	if !auth.HasScope(w, r, "update") {
+		return
+	}
+
+	db := h.dbFor(url)
+	if !exists(w, db, url) {
+		return
+	}
+
+	for _, changes := range []map[string][]any{replace, add} {
+		if err := validateProperties(changes); err != nil {
+			auth.Error(w, http.StatusBadRequest, "invalid_request", err.Error())
+			return
+		}
+	}
+
+	if err := db.Update(url, replace, add, delete, deleteAlls); err != nil {
+		slog.Error("update", slog.String("url", url), slog.Any("err", err))
+		auth.Error(w, http.StatusInternalServerError, "server_error", "the entry could not be updated")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func (h *micropubPostHandler) delete(w http.ResponseWriter, r *http.Request, url string) {
 	if !auth.HasScope(w, r, "delete") {
 		slog.Warn("request missing scope for delete", slog.Any("url", url))
 		return
 	}
 
-	if err := h.db.Delete(url); err != nil {
+	db := h.dbFor(url)
+	if !exists(w, db, url) {
+		return
+	}
+
+	if err := db.Delete(url); err != nil {
 		slog.Error("delete", slog.Any("url", url), slog.Any("err", err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		auth.Error(w, http.StatusInternalServerError, "server_error", "the entry could not be deleted")
 		return
 	}
 
@@ -281,14 +590,101 @@ func (h *micropubPostHandler) undelete(w http.ResponseWriter, r *http.Request, u
 		return
 	}
 
-	if err := h.db.Undelete(url); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	db := h.dbFor(url)
+	if !exists(w, db, url) {
+		return
+	}
+
+	if err := db.Undelete(url); err != nil {
+		slog.Error("undelete", slog.Any("url", url), slog.Any("err", err))
+		auth.Error(w, http.StatusInternalServerError, "server_error", "the entry could not be undeleted")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// restore is an extension to micropub, it returns the entry at url to how it
+// was at an earlier revision.
+func (h *micropubPostHandler) restore(w http.ResponseWriter, r *http.Request, url string, revision int) {
+	if !auth.HasScope(w, r, "update") {
+		return
+	}
+
+	db := h.dbFor(url)
+	if !exists(w, db, url) {
+		return
+	}
+
+	if revision < 1 {
+		auth.Error(w, http.StatusBadRequest, "invalid_request", "revision must be given")
+		return
+	}
+
+	if err := db.Restore(url, revision); err != nil {
+		slog.Error("restore", slog.String("url", url), slog.Int("revision", revision), slog.Any("err", err))
+		auth.Error(w, http.StatusInternalServerError, "server_error", "the entry could not be restored")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// purge is an extension to micropub, it permanently removes the entry at url
+// leaving only a record that it is gone.
+func (h *micropubPostHandler) purge(w http.ResponseWriter, r *http.Request, url string) {
+	if !auth.HasScope(w, r, "delete") {
+		return
+	}
+
+	db := h.dbFor(url)
+	if !exists(w, db, url) {
+		return
+	}
+
+	if err := db.Purge(url); err != nil {
+		slog.Error("purge", slog.String("url", url), slog.Any("err", err))
+		auth.Error(w, http.StatusInternalServerError, "server_error", "the entry could not be purged")
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// exists checks that there is an entry for url, otherwise it writes an error
+// response as the request can not be completed.
+func exists(w http.ResponseWriter, db postDB, url string) bool {
+	if url == "" {
+		auth.Error(w, http.StatusBadRequest, "invalid_request", "url must be given")
+		return false
+	}
+
+	if _, err := db.Entry(url); err != nil {
+		if !errors.Is(err, blog.ErrNotFound) {
+			slog.Error("entry for request", slog.String("url", url), slog.Any("err", err))
+			auth.Error(w, http.StatusInternalServerError, "server_error", "the entry could not be read")
+			return false
+		}
+
+		slog.Warn("entry for request", slog.String("url", url), slog.Any("err", err))
+		auth.Error(w, http.StatusBadRequest, "invalid_request", "no entry exists for "+url)
+		return false
+	}
+
+	return true
+}
+
+// propertyKey returns the property that a key given by a client is stored as.
+// The url of an entry can not be changed, so any given are kept as aliases that
+// redirect to it.
+func propertyKey(key string) string {
+	if key == "url" {
+		return "hx-aliases"
+	}
+
+	return key
+}
+
 func reservedKey(key string) bool {
 	return key == "access_token" || key == "action" || key == "url"
 }
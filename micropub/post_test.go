@@ -3,16 +3,22 @@ package micropub
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"io"
 	"io/ioutil"
+	"maps"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	"hawx.me/code/assert"
+	"hawx.me/code/tally-ho/blog"
 )
 
 func withScope(scope string, handler http.Handler) http.Handler {
@@ -27,6 +33,21 @@ type fakePostDB struct {
 	deleteAlls              map[string][][]string
 	deleted                 []string
 	undeleted               []string
+	restored                map[string][]int
+	purged                  []string
+	missing                 []string
+	err                     error
+}
+
+func (b *fakePostDB) Entry(url string) (map[string][]interface{}, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	if slices.Contains(b.missing, url) {
+		return nil, blog.ErrNotFound
+	}
+
+	return map[string][]interface{}{"url": {url}}, nil
 }
 
 func (b *fakePostDB) Create(data map[string][]interface{}) (string, error) {
@@ -58,6 +79,36 @@ func (b *fakePostDB) Undelete(url string) error {
 	return nil
 }
 
+func (b *fakePostDB) Restore(url string, version int) error {
+	if b.restored == nil {
+		b.restored = map[string][]int{}
+	}
+	b.restored[url] = append(b.restored[url], version)
+	return nil
+}
+
+func (b *fakePostDB) Purge(url string) error {
+	b.purged = append(b.purged, url)
+	return nil
+}
+
+type fakeIdempotencyStore struct {
+	locations map[string][2]string
+}
+
+func (s *fakeIdempotencyStore) Location(key string) (string, string, bool, error) {
+	created, ok := s.locations[key]
+	return created[0], created[1], ok, nil
+}
+
+func (s *fakeIdempotencyStore) Remember(key, hash, location string, expiresAt time.Time) error {
+	if s.locations == nil {
+		s.locations = map[string][2]string{}
+	}
+	s.locations[key] = [2]string{location, hash}
+	return nil
+}
+
 type fakeFileWriter struct {
 	data []string
 }
@@ -81,6 +132,11 @@ func newJSONRequest(body string) *http.Request {
 	return req
 }
 
+func withIdempotencyKey(key string, req *http.Request) *http.Request {
+	req.Header.Set("Idempotency-Key", key)
+	return req
+}
+
 type multipartFile struct {
 	key, name, value string
 }
@@ -89,8 +145,9 @@ func newMultipartRequest(fields url.Values, files []multipartFile) *http.Request
 	var buf bytes.Buffer
 	writer := multipart.NewWriter(&buf)
 
-	for key, values := range fields {
-		for _, value := range values {
+	// keys are written in order, so that the same fields give the same body
+	for _, key := range slices.Sorted(maps.Keys(fields)) {
+		for _, value := range fields[key] {
 			part, err := writer.CreateFormField(key)
 			if err != nil {
 				panic(err)
@@ -148,7 +205,7 @@ func TestPostEntry(t *testing.T) {
 			assert := assert.New(t)
 			blog := &fakePostDB{}
 
-			handler := withScope("create", postHandler(blog, nil))
+			handler := withScope("create", postHandler(blog, nil, &fakeIdempotencyStore{}, nil))
 
 			w := httptest.NewRecorder()
 			handler.ServeHTTP(w, req)
@@ -168,11 +225,209 @@ func TestPostEntry(t *testing.T) {
 
 				_, ok := data["url"]
 				assert.False(ok)
+				assert.Len(data["hx-aliases"], 1)
 			}
 		})
 	}
 }
 
+func TestPostEntryWithPhotoAlt(t *testing.T) {
+	testCases := map[string]*http.Request{
+		"url-encoded-form": newFormRequest(url.Values{
+			"h":            {"entry"},
+			"photo":        {"http://example.com/a.jpg"},
+			"mp-photo-alt": {"a cat"},
+		}),
+		"url-encoded-form with photo[alt]": newFormRequest(url.Values{
+			"h":            {"entry"},
+			"photo[value]": {"http://example.com/a.jpg"},
+			"photo[alt]":   {"a cat"},
+		}),
+		"multipart-form": newMultipartRequest(url.Values{
+			"h":          {"entry"},
+			"photo[alt]": {"a cat"},
+		}, []multipartFile{{"photo", "a.jpg", "this is an image"}}),
+	}
+
+	for name, req := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			blog := &fakePostDB{}
+
+			handler := withScope("create", postHandler(blog, &fakeFileWriter{}, &fakeIdempotencyStore{}, nil))
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			resp := w.Result()
+
+			assert.Equal(http.StatusCreated, resp.StatusCode)
+
+			if assert.Len(blog.datas, 1) {
+				data := blog.datas[0]
+
+				assert.Equal([]interface{}{"http://example.com/a.jpg"}, data["photo"])
+				assert.Equal([]interface{}{"a cat"}, data["mp-photo-alt"])
+			}
+		})
+	}
+}
+
+func TestPostEntryWithBlankPhotoAlt(t *testing.T) {
+	testCases := map[string]*http.Request{
+		"mp-photo-alt[]": newFormRequest(url.Values{
+			"h":              {"entry"},
+			"photo[]":        {"http://example.com/a.jpg", "http://example.com/b.jpg"},
+			"mp-photo-alt[]": {"", "a dog"},
+		}),
+		"photo[alt][]": newFormRequest(url.Values{
+			"h":              {"entry"},
+			"photo[value][]": {"http://example.com/a.jpg", "http://example.com/b.jpg"},
+			"photo[alt][]":   {"", "a dog"},
+		}),
+	}
+
+	for name, req := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			blog := &fakePostDB{}
+
+			handler := withScope("create", postHandler(blog, nil, &fakeIdempotencyStore{}, nil))
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			resp := w.Result()
+
+			assert.Equal(http.StatusCreated, resp.StatusCode)
+
+			if assert.Len(blog.datas, 1) {
+				data := blog.datas[0]
+
+				assert.Equal([]interface{}{"http://example.com/a.jpg", "http://example.com/b.jpg"}, data["photo"])
+				assert.Equal([]interface{}{"", "a dog"}, data["mp-photo-alt"])
+			}
+		})
+	}
+}
+
+func TestPostEntryWithPhotoObject(t *testing.T) {
+	assert := assert.New(t)
+	blog := &fakePostDB{}
+
+	handler := withScope("create", postHandler(blog, nil, &fakeIdempotencyStore{}, nil))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, newJSONRequest(`{
+  "type": ["h-entry"],
+  "properties": {
+    "photo": [{"value": "http://example.com/a.jpg", "alt": "a cat"}]
+  }
+}`))
+	resp := w.Result()
+
+	assert.Equal(http.StatusCreated, resp.StatusCode)
+
+	if assert.Len(blog.datas, 1) {
+		assert.Equal([]interface{}{
+			map[string]interface{}{"value": "http://example.com/a.jpg", "alt": "a cat"},
+		}, blog.datas[0]["photo"])
+	}
+}
+
+func TestPostEvent(t *testing.T) {
+	assert := assert.New(t)
+	blog := &fakePostDB{}
+
+	handler := withScope("create", postHandler(blog, nil, &fakeIdempotencyStore{}, nil))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, newFormRequest(url.Values{
+		"h":        {"event"},
+		"name":     {"Go meetup"},
+		"start":    {"2020-10-08T18:30:00+01:00"},
+		"end":      {"2020-10-08T21:00:00+01:00"},
+		"location": {"The Pub"},
+		"summary":  {"Talks and drinks"},
+	}))
+	resp := w.Result()
+
+	assert.Equal(http.StatusCreated, resp.StatusCode)
+
+	if assert.Len(blog.datas, 1) {
+		data := blog.datas[0]
+
+		assert.Equal("event", data["h"][0])
+		assert.Equal("Go meetup", data["name"][0])
+		assert.Equal("2020-10-08T18:30:00+01:00", data["start"][0])
+		assert.Equal("2020-10-08T21:00:00+01:00", data["end"][0])
+		assert.Equal("The Pub", data["location"][0])
+		assert.Equal("Talks and drinks", data["summary"][0])
+	}
+}
+
+func TestPostAudio(t *testing.T) {
+	assert := assert.New(t)
+	blog := &fakePostDB{}
+
+	handler := withScope("create", postHandler(blog, nil, &fakeIdempotencyStore{}, nil))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, newFormRequest(url.Values{
+		"h":     {"entry"},
+		"audio": {"https://example.com/a.mp3"},
+	}))
+	resp := w.Result()
+
+	assert.Equal(http.StatusCreated, resp.StatusCode)
+	assert.Len(blog.datas, 1)
+}
+
+func TestPostRSVPInterested(t *testing.T) {
+	assert := assert.New(t)
+	blog := &fakePostDB{}
+
+	handler := withScope("create", postHandler(blog, nil, &fakeIdempotencyStore{}, nil))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, newFormRequest(url.Values{
+		"h":           {"entry"},
+		"rsvp":        {"interested"},
+		"in-reply-to": {"https://example.com/event"},
+	}))
+	resp := w.Result()
+
+	assert.Equal(http.StatusCreated, resp.StatusCode)
+	assert.Len(blog.datas, 1)
+}
+
+func TestPostReview(t *testing.T) {
+	assert := assert.New(t)
+	blog := &fakePostDB{}
+
+	handler := withScope("create", postHandler(blog, nil, &fakeIdempotencyStore{}, nil))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, newJSONRequest(`{
+  "type": ["h-review"],
+  "properties": {
+    "item": [{"type": ["h-product"], "properties": {"name": ["A kettle"]}}],
+    "rating": [4],
+    "best": ["5"],
+    "content": ["It boils water"]
+  }
+}`))
+	resp := w.Result()
+
+	assert.Equal(http.StatusCreated, resp.StatusCode)
+
+	if assert.Len(blog.datas, 1) {
+		data := blog.datas[0]
+
+		assert.Equal("review", data["h"][0])
+		assert.Equal(float64(4), data["rating"][0])
+		assert.Equal("5", data["best"][0])
+	}
+}
+
 func TestPostEntryMissingScope(t *testing.T) {
 	testCases := map[string]*http.Request{
 		"url-encoded-form": newFormRequest(url.Values{
@@ -205,7 +460,7 @@ func TestPostEntryMissingScope(t *testing.T) {
 			assert := assert.New(t)
 			blog := &fakePostDB{}
 
-			handler := postHandler(blog, nil)
+			handler := postHandler(blog, nil, &fakeIdempotencyStore{}, nil)
 
 			w := httptest.NewRecorder()
 			handler.ServeHTTP(w, req)
@@ -217,6 +472,259 @@ func TestPostEntryMissingScope(t *testing.T) {
 	}
 }
 
+func TestPostEntryWithClient(t *testing.T) {
+	assert := assert.New(t)
+	db := &fakePostDB{}
+
+	handler := withScope("create", postHandler(db, nil, &fakeIdempotencyStore{}, nil))
+
+	req := newFormRequest(url.Values{"content": {"hey"}})
+	req = req.WithContext(context.WithValue(req.Context(), "__hawx.me/code/tally-ho:ClientID__", "https://client.example.com/"))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(http.StatusCreated, w.Result().StatusCode)
+
+	if assert.Len(db.datas, 1) {
+		assert.Equal([]any{"https://client.example.com/"}, db.datas[0]["hx-client-id"])
+	}
+}
+
+func TestPostEntryRepeated(t *testing.T) {
+	testCases := map[string]struct {
+		first, second *http.Request
+	}{
+		"same body": {
+			first: newFormRequest(url.Values{
+				"h":       {"entry"},
+				"content": {"This is a test"},
+			}),
+			second: newFormRequest(url.Values{
+				"h":       {"entry"},
+				"content": {"This is a test"},
+			}),
+		},
+		"same idempotency key": {
+			first: withIdempotencyKey("abc", newJSONRequest(`{
+  "type": ["h-entry"],
+  "properties": {"content": ["This is a test"]}
+}`)),
+			second: withIdempotencyKey("abc", newJSONRequest(`{
+  "type": ["h-entry"],
+  "properties": {"content": ["This is a test"]}
+}`)),
+		},
+		"same multipart idempotency key": {
+			first: withIdempotencyKey("abc", newMultipartRequest(url.Values{
+				"h":       {"entry"},
+				"content": {"This is a test"},
+			}, []multipartFile{{"photo", "whatever.png", "this is an image"}})),
+			second: withIdempotencyKey("abc", newMultipartRequest(url.Values{
+				"h":       {"entry"},
+				"content": {"This is a test"},
+			}, []multipartFile{{"photo", "whatever.png", "this is an image"}})),
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			db := &fakePostDB{}
+			fw := &fakeFileWriter{}
+
+			handler := withScope("create", postHandler(db, fw, &fakeIdempotencyStore{}, nil))
+
+			for _, req := range []*http.Request{tc.first, tc.second} {
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, req)
+
+				resp := w.Result()
+				assert.Equal(http.StatusCreated, resp.StatusCode)
+				assert.Equal("http://example.com/blog/p/1", resp.Header.Get("Location"))
+			}
+
+			assert.Len(db.datas, 1)
+		})
+	}
+}
+
+func TestPostEntryRepeatedWithDifferentKey(t *testing.T) {
+	assert := assert.New(t)
+	db := &fakePostDB{}
+
+	handler := withScope("create", postHandler(db, nil, &fakeIdempotencyStore{}, nil))
+
+	for _, key := range []string{"abc", "def"} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, withIdempotencyKey(key, newFormRequest(url.Values{
+			"h":       {"entry"},
+			"content": {"This is a test"},
+		})))
+
+		assert.Equal(http.StatusCreated, w.Result().StatusCode)
+	}
+
+	assert.Len(db.datas, 2)
+}
+
+func TestPostEntryRepeatedKeyWithDifferentBody(t *testing.T) {
+	assert := assert.New(t)
+	db := &fakePostDB{}
+
+	handler := withScope("create", postHandler(db, nil, &fakeIdempotencyStore{}, nil))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, withIdempotencyKey("abc", newJSONRequest(`{
+  "type": ["h-entry"],
+  "properties": {"content": ["This is a test"]}
+}`)))
+	assert.Equal(http.StatusCreated, w.Result().StatusCode)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, withIdempotencyKey("abc", newJSONRequest(`{
+  "type": ["h-entry"],
+  "properties": {"content": ["This is a test, again"]}
+}`)))
+	resp := w.Result()
+	assert.Equal(http.StatusUnprocessableEntity, resp.StatusCode)
+	assert.Equal("", resp.Header.Get("Location"))
+
+	assert.Len(db.datas, 1)
+}
+
+func TestPostEntryRepeatedMissingScope(t *testing.T) {
+	assert := assert.New(t)
+	db := &fakePostDB{}
+	store := &fakeIdempotencyStore{}
+
+	for _, tc := range []struct {
+		scope  string
+		status int
+	}{
+		{"create", http.StatusCreated},
+		{"update", http.StatusUnauthorized},
+	} {
+		w := httptest.NewRecorder()
+		withScope(tc.scope, postHandler(db, nil, store, nil)).ServeHTTP(w, withIdempotencyKey("abc", newFormRequest(url.Values{
+			"h":       {"entry"},
+			"content": {"This is a test"},
+		})))
+
+		assert.Equal(tc.status, w.Result().StatusCode)
+	}
+
+	assert.Len(db.datas, 1)
+}
+
+func TestPostEntryRepeatedAfterFailure(t *testing.T) {
+	assert := assert.New(t)
+	db := &fakePostDB{}
+
+	handler := postHandler(db, nil, &fakeIdempotencyStore{}, nil)
+
+	for _, handler := range []http.Handler{handler, withScope("create", handler)} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, withIdempotencyKey("abc", newFormRequest(url.Values{
+			"h":       {"entry"},
+			"content": {"This is a test"},
+		})))
+	}
+
+	assert.Len(db.datas, 1)
+}
+
+func TestPostEntryInvalid(t *testing.T) {
+	testCases := map[string]*http.Request{
+		"like without url": newJSONRequest(`{
+  "type": ["h-entry"],
+  "properties": {
+    "like-of": [""]
+  }
+}`),
+		"bad published": newFormRequest(url.Values{
+			"h":         {"entry"},
+			"content":   {"This is a test"},
+			"published": {"last tuesday"},
+		}),
+		"non-string in-reply-to": newJSONRequest(`{
+  "type": ["h-entry"],
+  "properties": {
+    "in-reply-to": [{"type": ["h-cite"], "properties": {}}],
+    "content": ["This is a test"]
+  }
+}`),
+		"no content": newFormRequest(url.Values{
+			"h":        {"entry"},
+			"category": {"test"},
+		}),
+		"unsupported type": newFormRequest(url.Values{
+			"h":       {"product"},
+			"content": {"This is a test"},
+		}),
+		"photo without value": newJSONRequest(`{
+  "type": ["h-entry"],
+  "properties": {
+    "photo": [{"alt": "a cat"}]
+  }
+}`),
+		"event without start": newFormRequest(url.Values{
+			"h":    {"event"},
+			"name": {"Go meetup"},
+		}),
+		"event ending before start": newFormRequest(url.Values{
+			"h":     {"event"},
+			"name":  {"Go meetup"},
+			"start": {"2020-10-08T18:30:00+01:00"},
+			"end":   {"2020-10-08T17:00:00+01:00"},
+		}),
+		"review without item": newFormRequest(url.Values{
+			"h":      {"review"},
+			"rating": {"4"},
+		}),
+		"review with rating out of range": newFormRequest(url.Values{
+			"h":      {"review"},
+			"item":   {"https://example.com/kettle"},
+			"rating": {"6"},
+		}),
+		"review with rating not a number": newFormRequest(url.Values{
+			"h":      {"review"},
+			"item":   {"https://example.com/kettle"},
+			"rating": {"good"},
+		}),
+		"rsvp without in-reply-to": newFormRequest(url.Values{
+			"h":       {"entry"},
+			"content": {"This is a test"},
+			"rsvp":    {"yes"},
+		}),
+	}
+
+	for name, req := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			db := &fakePostDB{}
+
+			handler := withScope("create", postHandler(db, nil, &fakeIdempotencyStore{}, nil))
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			resp := w.Result()
+			assert.Equal(http.StatusBadRequest, resp.StatusCode)
+			assert.Equal("application/json", resp.Header.Get("Content-Type"))
+
+			var v struct {
+				Error            string `json:"error"`
+				ErrorDescription string `json:"error_description"`
+			}
+			assert.Nil(json.NewDecoder(resp.Body).Decode(&v))
+			assert.Equal("invalid_request", v.Error)
+			assert.NotEqual("", v.ErrorDescription)
+
+			assert.Len(db.datas, 0)
+		})
+	}
+}
+
 func TestPostEntryMultipartFormWithMedia(t *testing.T) {
 	for _, key := range []string{"photo", "video", "audio"} {
 		t.Run(key, func(t *testing.T) {
@@ -225,7 +733,7 @@ func TestPostEntryMultipartFormWithMedia(t *testing.T) {
 			db := &fakePostDB{}
 			fw := &fakeFileWriter{}
 
-			handler := withScope("create", postHandler(db, fw))
+			handler := withScope("create", postHandler(db, fw, &fakeIdempotencyStore{}, nil))
 
 			req := newMultipartRequest(url.Values{
 				"h":       {"entry"},
@@ -262,7 +770,7 @@ func TestPostEntryMultipartFormWithMediaMissingScope(t *testing.T) {
 			db := &fakePostDB{}
 			fw := &fakeFileWriter{}
 
-			handler := postHandler(db, fw)
+			handler := postHandler(db, fw, &fakeIdempotencyStore{}, nil)
 
 			req := newMultipartRequest(url.Values{
 				"h":       {"entry"},
@@ -280,6 +788,27 @@ func TestPostEntryMultipartFormWithMediaMissingScope(t *testing.T) {
 	}
 }
 
+func TestPostEntryMultipartFormWithMediaWrongScope(t *testing.T) {
+	assert := assert.New(t)
+	db := &fakePostDB{}
+	fw := &fakeFileWriter{}
+
+	handler := withScope("delete", postHandler(db, fw, &fakeIdempotencyStore{}, nil))
+
+	req := newMultipartRequest(url.Values{
+		"h":       {"entry"},
+		"content": {"This is a test"},
+	}, []multipartFile{{"photo", "whatever.png", "this is an image"}})
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	resp := w.Result()
+	assert.Equal(http.StatusUnauthorized, resp.StatusCode)
+	assert.Len(db.datas, 0)
+	assert.Len(fw.data, 0)
+}
+
 func TestPostEntryMultipartFormWithMultiplePhotos(t *testing.T) {
 	for _, key := range []string{"photo", "video", "audio"} {
 		t.Run(key, func(t *testing.T) {
@@ -287,7 +816,7 @@ func TestPostEntryMultipartFormWithMultiplePhotos(t *testing.T) {
 			db := &fakePostDB{}
 			fw := &fakeFileWriter{}
 
-			handler := withScope("create", postHandler(db, fw))
+			handler := withScope("create", postHandler(db, fw, &fakeIdempotencyStore{}, nil))
 
 			req := newMultipartRequest(url.Values{
 				"h":       {"entry"},
@@ -301,22 +830,99 @@ func TestPostEntryMultipartFormWithMultiplePhotos(t *testing.T) {
 			handler.ServeHTTP(w, req)
 
 			resp := w.Result()
-			assert.Equal(http.StatusCreated, resp.StatusCode)
-			assert.Equal("http://example.com/blog/p/1", resp.Header.Get("Location"))
-
-			if assert.Len(db.datas, 1) {
-				data := db.datas[0]
-
-				assert.Equal("entry", data["h"][0])
-				assert.Equal("This is a test", data["content"][0])
-				assert.Equal("http://example.com/1.jpg", data[key][0])
-				assert.Equal("http://example.com/2.jpg", data[key][1])
-			}
-
-			if assert.Len(fw.data, 2) {
-				assert.Equal("the first file", fw.data[0])
-				assert.Equal("the second image", fw.data[1])
-			}
+			assert.Equal(http.StatusCreated, resp.StatusCode)
+			assert.Equal("http://example.com/blog/p/1", resp.Header.Get("Location"))
+
+			if assert.Len(db.datas, 1) {
+				data := db.datas[0]
+
+				assert.Equal("entry", data["h"][0])
+				assert.Equal("This is a test", data["content"][0])
+				assert.Equal("http://example.com/1.jpg", data[key][0])
+				assert.Equal("http://example.com/2.jpg", data[key][1])
+			}
+
+			if assert.Len(fw.data, 2) {
+				assert.Equal("the first file", fw.data[0])
+				assert.Equal("the second image", fw.data[1])
+			}
+		})
+	}
+}
+
+func TestPostEntryMultipartFormWithPhotoAlts(t *testing.T) {
+	assert := assert.New(t)
+	db := &fakePostDB{}
+	fw := &fakeFileWriter{}
+
+	handler := withScope("create", postHandler(db, fw, &fakeIdempotencyStore{}, nil))
+
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	for _, part := range []struct{ key, name, value string }{
+		{"h", "", "entry"},
+		{"photo", "a.jpg", "the first image"},
+		{"photo[alt]", "", "a cat"},
+		{"photo[value]", "", "http://example.com/b.jpg"},
+		{"photo[alt][]", "", "a dog"},
+		{"photo[]", "c.jpg", "the second image"},
+	} {
+		var w io.Writer
+		if part.name != "" {
+			w, _ = writer.CreateFormFile(part.key, part.name)
+		} else {
+			w, _ = writer.CreateFormField(part.key)
+		}
+		io.WriteString(w, part.value)
+	}
+	writer.Close()
+
+	req := httptest.NewRequest("POST", "http://localhost/", &buf)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	resp := w.Result()
+	assert.Equal(http.StatusCreated, resp.StatusCode)
+
+	if assert.Len(db.datas, 1) {
+		data := db.datas[0]
+
+		assert.Equal([]interface{}{
+			"http://example.com/a.jpg",
+			"http://example.com/b.jpg",
+			"http://example.com/c.jpg",
+		}, data["photo"])
+		assert.Equal([]interface{}{"a cat", "a dog", ""}, data["mp-photo-alt"])
+	}
+}
+
+type failingFileWriter struct{}
+
+func (failingFileWriter) WriteFile(name, contentType string, r io.Reader) (string, error) {
+	return "", errors.New("nope")
+}
+
+func TestPostEntryMultipartFormWhenMediaCanNotBeWritten(t *testing.T) {
+	for _, key := range []string{"photo", "video", "audio"} {
+		t.Run(key, func(t *testing.T) {
+			assert := assert.New(t)
+			db := &fakePostDB{}
+
+			handler := withScope("create", postHandler(db, failingFileWriter{}, &fakeIdempotencyStore{}, nil))
+
+			req := newMultipartRequest(url.Values{
+				"h":       {"entry"},
+				"content": {"This is a test"},
+			}, []multipartFile{{key, "whatever.png", "this is an image"}})
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			resp := w.Result()
+			assert.Equal(http.StatusInternalServerError, resp.StatusCode)
+			assert.Len(db.datas, 0)
 		})
 	}
 }
@@ -347,7 +953,7 @@ func TestPostEntryWithEmptyValues(t *testing.T) {
 			assert := assert.New(t)
 			blog := &fakePostDB{}
 
-			handler := withScope("create", postHandler(blog, nil))
+			handler := withScope("create", postHandler(blog, nil, &fakeIdempotencyStore{}, nil))
 
 			w := httptest.NewRecorder()
 			handler.ServeHTTP(w, req)
@@ -375,7 +981,7 @@ func TestUpdateEntry(t *testing.T) {
 		deleteAlls: map[string][][]string{},
 	}
 
-	handler := withScope("update", postHandler(db, nil))
+	handler := withScope("update", postHandler(db, nil, &fakeIdempotencyStore{}, nil))
 
 	req := newJSONRequest(`{
   "action": "update",
@@ -413,6 +1019,49 @@ func TestUpdateEntry(t *testing.T) {
 	}
 }
 
+func TestUpdateEntryAliases(t *testing.T) {
+	assert := assert.New(t)
+	db := &fakePostDB{
+		adds:       map[string][]map[string][]interface{}{},
+		deletes:    map[string][]map[string][]interface{}{},
+		replaces:   map[string][]map[string][]interface{}{},
+		deleteAlls: map[string][][]string{},
+	}
+
+	handler := withScope("update", postHandler(db, nil, &fakeIdempotencyStore{}, nil))
+
+	req := newJSONRequest(`{
+  "action": "update",
+  "url": "https://example.com/blog/p/100",
+  "add": {
+    "url": ["https://example.com/old/100"]
+  },
+  "delete": {
+    "url": ["https://example.com/older/100"]
+  }
+}`)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	resp := w.Result()
+	assert.Equal(http.StatusNoContent, resp.StatusCode)
+
+	add, ok := db.adds["https://example.com/blog/p/100"]
+	if assert.True(ok) && assert.Len(add, 1) {
+		assert.Equal(map[string][]interface{}{
+			"hx-aliases": {"https://example.com/old/100"},
+		}, add[0])
+	}
+
+	delete, ok := db.deletes["https://example.com/blog/p/100"]
+	if assert.True(ok) && assert.Len(delete, 1) {
+		assert.Equal(map[string][]interface{}{
+			"hx-aliases": {"https://example.com/older/100"},
+		}, delete[0])
+	}
+}
+
 func TestUpdateEntryMissingScope(t *testing.T) {
 	assert := assert.New(t)
 	db := &fakePostDB{
@@ -422,7 +1071,7 @@ func TestUpdateEntryMissingScope(t *testing.T) {
 		deleteAlls: map[string][][]string{},
 	}
 
-	handler := postHandler(db, nil)
+	handler := postHandler(db, nil, &fakeIdempotencyStore{}, nil)
 
 	req := newJSONRequest(`{
   "action": "update",
@@ -463,7 +1112,7 @@ func TestUpdateEntryDelete(t *testing.T) {
 		deleteAlls: map[string][][]string{},
 	}
 
-	handler := withScope("update", postHandler(db, nil))
+	handler := withScope("update", postHandler(db, nil, &fakeIdempotencyStore{}, nil))
 
 	req := newJSONRequest(`{
   "action": "update",
@@ -500,7 +1149,7 @@ func TestUpdateEntryDelete(t *testing.T) {
 }
 
 func TestUpdateEntryInvalidDelete(t *testing.T) {
-	handler := withScope("update", postHandler(nil, nil))
+	handler := withScope("update", postHandler(nil, nil, &fakeIdempotencyStore{}, nil))
 
 	testCases := map[string]string{
 		"array with non-string": `[1]`,
@@ -524,12 +1173,261 @@ func TestUpdateEntryInvalidDelete(t *testing.T) {
 	}
 }
 
+func TestUpdateEntryForm(t *testing.T) {
+	testCases := map[string]func(url.Values) *http.Request{
+		"url-encoded-form": newFormRequest,
+		"multipart-form": func(qs url.Values) *http.Request {
+			return newMultipartRequest(qs, nil)
+		},
+	}
+
+	for name, newRequest := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			db := &fakePostDB{
+				adds:       map[string][]map[string][]interface{}{},
+				deletes:    map[string][]map[string][]interface{}{},
+				replaces:   map[string][]map[string][]interface{}{},
+				deleteAlls: map[string][][]string{},
+			}
+
+			handler := withScope("update", postHandler(db, nil, &fakeIdempotencyStore{}, nil))
+
+			req := newRequest(url.Values{
+				"action":                  {"update"},
+				"url":                     {"https://example.com/blog/p/100"},
+				"replace[content][]":      {"hello moon"},
+				"add[syndication][]":      {"http://somewhere.com", "http://else.com"},
+				"delete[category][]":      {"this"},
+				"delete[]":                {"not-important"},
+				"replace[access_token][]": {"nope"},
+			})
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			resp := w.Result()
+			assert.Equal(http.StatusNoContent, resp.StatusCode)
+			assert.Len(db.datas, 0)
+
+			replace, ok := db.replaces["https://example.com/blog/p/100"]
+			if assert.True(ok) && assert.Len(replace, 1) {
+				assert.Equal(map[string][]interface{}{
+					"content": {"hello moon"},
+				}, replace[0])
+			}
+
+			add, ok := db.adds["https://example.com/blog/p/100"]
+			if assert.True(ok) && assert.Len(add, 1) {
+				assert.Equal(map[string][]interface{}{
+					"syndication": {"http://somewhere.com", "http://else.com"},
+				}, add[0])
+			}
+
+			delete, ok := db.deletes["https://example.com/blog/p/100"]
+			if assert.True(ok) && assert.Len(delete, 1) {
+				assert.Equal(map[string][]interface{}{
+					"category": {"this"},
+				}, delete[0])
+			}
+
+			deleteAlls, ok := db.deleteAlls["https://example.com/blog/p/100"]
+			if assert.True(ok) && assert.Len(deleteAlls, 1) {
+				assert.Equal([]string{"not-important"}, deleteAlls[0])
+			}
+		})
+	}
+}
+
+func TestUpdateEntryFormMissingScope(t *testing.T) {
+	testCases := map[string]func(url.Values) *http.Request{
+		"url-encoded-form": newFormRequest,
+		"multipart-form": func(qs url.Values) *http.Request {
+			return newMultipartRequest(qs, nil)
+		},
+	}
+
+	for name, newRequest := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			db := &fakePostDB{
+				adds:       map[string][]map[string][]interface{}{},
+				deletes:    map[string][]map[string][]interface{}{},
+				replaces:   map[string][]map[string][]interface{}{},
+				deleteAlls: map[string][][]string{},
+			}
+
+			handler := withScope("create", postHandler(db, nil, &fakeIdempotencyStore{}, nil))
+
+			req := newRequest(url.Values{
+				"action":             {"update"},
+				"url":                {"https://example.com/blog/p/100"},
+				"replace[content][]": {"hello moon"},
+			})
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			resp := w.Result()
+			assert.Equal(http.StatusUnauthorized, resp.StatusCode)
+			assert.Len(db.datas, 0)
+
+			_, ok := db.replaces["https://example.com/blog/p/100"]
+			assert.False(ok)
+		})
+	}
+}
+
+func TestUpdateEntryMultipartFormWithMedia(t *testing.T) {
+	for _, key := range []string{"photo", "video", "audio"} {
+		t.Run(key, func(t *testing.T) {
+			assert := assert.New(t)
+			db := &fakePostDB{
+				adds:       map[string][]map[string][]interface{}{},
+				deletes:    map[string][]map[string][]interface{}{},
+				replaces:   map[string][]map[string][]interface{}{},
+				deleteAlls: map[string][][]string{},
+			}
+			fw := &fakeFileWriter{}
+
+			handler := withScope("update", postHandler(db, fw, &fakeIdempotencyStore{}, nil))
+
+			req := newMultipartRequest(url.Values{
+				"action": {"update"},
+				"url":    {"https://example.com/blog/p/100"},
+			}, []multipartFile{
+				{"add[" + key + "][]", "1.jpg", "the first file"},
+				{"replace[" + key + "][]", "2.jpg", "the second image"},
+			})
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			resp := w.Result()
+			assert.Equal(http.StatusNoContent, resp.StatusCode)
+
+			add, ok := db.adds["https://example.com/blog/p/100"]
+			if assert.True(ok) && assert.Len(add, 1) {
+				assert.Equal("http://example.com/1.jpg", add[0][key][0])
+			}
+
+			replace, ok := db.replaces["https://example.com/blog/p/100"]
+			if assert.True(ok) && assert.Len(replace, 1) {
+				assert.Equal("http://example.com/2.jpg", replace[0][key][0])
+			}
+
+			if assert.Len(fw.data, 2) {
+				assert.Equal("the first file", fw.data[0])
+				assert.Equal("the second image", fw.data[1])
+			}
+		})
+	}
+}
+
+func TestUpdateEntryInvalid(t *testing.T) {
+	testCases := map[string]string{
+		"missing url": `{
+  "action": "update",
+  "replace": {"content": ["hello moon"]}
+}`,
+		"unknown url": `{
+  "action": "update",
+  "url": "https://example.com/blog/p/404",
+  "replace": {"content": ["hello moon"]}
+}`,
+		"bad published": `{
+  "action": "update",
+  "url": "https://example.com/blog/p/100",
+  "replace": {"published": ["soon"]}
+}`,
+		"bad like-of": `{
+  "action": "update",
+  "url": "https://example.com/blog/p/100",
+  "add": {"like-of": ["not a url"]}
+}`,
+	}
+
+	for name, body := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			db := &fakePostDB{
+				adds:       map[string][]map[string][]interface{}{},
+				deletes:    map[string][]map[string][]interface{}{},
+				replaces:   map[string][]map[string][]interface{}{},
+				deleteAlls: map[string][][]string{},
+				missing:    []string{"https://example.com/blog/p/404"},
+			}
+
+			handler := withScope("update", postHandler(db, nil, &fakeIdempotencyStore{}, nil))
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, newJSONRequest(body))
+
+			resp := w.Result()
+			assert.Equal(http.StatusBadRequest, resp.StatusCode)
+
+			var v struct {
+				Error string `json:"error"`
+			}
+			json.NewDecoder(resp.Body).Decode(&v)
+			assert.Equal("invalid_request", v.Error)
+
+			assert.Len(db.replaces, 0)
+			assert.Len(db.adds, 0)
+		})
+	}
+}
+
+func TestDeleteEntryUnknown(t *testing.T) {
+	assert := assert.New(t)
+	db := &fakePostDB{missing: []string{"https://example.com/blog/p/404"}}
+
+	handler := withScope("delete", postHandler(db, nil, &fakeIdempotencyStore{}, nil))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, newFormRequest(url.Values{
+		"action": {"delete"},
+		"url":    {"https://example.com/blog/p/404"},
+	}))
+
+	resp := w.Result()
+	assert.Equal(http.StatusBadRequest, resp.StatusCode)
+	assert.Len(db.deleted, 0)
+}
+
+func TestDeleteEntryWhenEntryCanNotBeRead(t *testing.T) {
+	assert := assert.New(t)
+	db := &fakePostDB{err: errors.New("database is locked")}
+
+	handler := withScope("delete", postHandler(db, nil, &fakeIdempotencyStore{}, nil))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, newFormRequest(url.Values{
+		"action": {"delete"},
+		"url":    {"https://example.com/blog/p/1"},
+	}))
+
+	resp := w.Result()
+	assert.Equal(http.StatusInternalServerError, resp.StatusCode)
+
+	var v struct {
+		Error string `json:"error"`
+	}
+	json.NewDecoder(resp.Body).Decode(&v)
+	assert.Equal("server_error", v.Error)
+	assert.Len(db.deleted, 0)
+}
+
 func TestDeleteEntry(t *testing.T) {
 	testCases := map[string]*http.Request{
 		"url-encoded-form": newFormRequest(url.Values{
 			"action": {"delete"},
 			"url":    {"https://example.com/blog/p/1"},
 		}),
+		"multipart-form": newMultipartRequest(url.Values{
+			"action": {"delete"},
+			"url":    {"https://example.com/blog/p/1"},
+		}, nil),
 		"json": newJSONRequest(`{"action": "delete", "url": "https://example.com/blog/p/1"}`),
 	}
 
@@ -538,7 +1436,7 @@ func TestDeleteEntry(t *testing.T) {
 			assert := assert.New(t)
 			db := &fakePostDB{}
 
-			handler := withScope("delete", postHandler(db, nil))
+			handler := withScope("delete", postHandler(db, nil, &fakeIdempotencyStore{}, nil))
 
 			w := httptest.NewRecorder()
 			handler.ServeHTTP(w, req)
@@ -567,7 +1465,7 @@ func TestDeleteEntryMissingScope(t *testing.T) {
 			assert := assert.New(t)
 			db := &fakePostDB{}
 
-			handler := postHandler(db, nil)
+			handler := postHandler(db, nil, &fakeIdempotencyStore{}, nil)
 
 			w := httptest.NewRecorder()
 			handler.ServeHTTP(w, req)
@@ -585,6 +1483,10 @@ func TestUndeleteEntry(t *testing.T) {
 			"action": {"undelete"},
 			"url":    {"https://example.com/blog/p/1"},
 		}),
+		"multipart-form": newMultipartRequest(url.Values{
+			"action": {"undelete"},
+			"url":    {"https://example.com/blog/p/1"},
+		}, nil),
 		"json": newJSONRequest(`{"action": "undelete", "url": "https://example.com/blog/p/1"}`),
 	}
 
@@ -593,7 +1495,7 @@ func TestUndeleteEntry(t *testing.T) {
 			assert := assert.New(t)
 			db := &fakePostDB{}
 
-			handler := withScope("delete", postHandler(db, nil))
+			handler := withScope("delete", postHandler(db, nil, &fakeIdempotencyStore{}, nil))
 
 			w := httptest.NewRecorder()
 			handler.ServeHTTP(w, req)
@@ -622,7 +1524,7 @@ func TestUndeleteEntryMissingScope(t *testing.T) {
 			assert := assert.New(t)
 			db := &fakePostDB{}
 
-			handler := postHandler(db, nil)
+			handler := postHandler(db, nil, &fakeIdempotencyStore{}, nil)
 
 			w := httptest.NewRecorder()
 			handler.ServeHTTP(w, req)
@@ -633,3 +1535,111 @@ func TestUndeleteEntryMissingScope(t *testing.T) {
 		})
 	}
 }
+
+func TestRestoreEntry(t *testing.T) {
+	testCases := map[string]*http.Request{
+		"url-encoded-form": newFormRequest(url.Values{
+			"action":   {"restore"},
+			"url":      {"https://example.com/blog/p/1"},
+			"revision": {"2"},
+		}),
+		"json": newJSONRequest(`{"action": "restore", "url": "https://example.com/blog/p/1", "revision": 2}`),
+	}
+
+	for name, req := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			db := &fakePostDB{}
+
+			handler := withScope("update", postHandler(db, nil, &fakeIdempotencyStore{}, nil))
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			resp := w.Result()
+			assert.Equal(http.StatusNoContent, resp.StatusCode)
+			assert.Equal([]int{2}, db.restored["https://example.com/blog/p/1"])
+		})
+	}
+}
+
+func TestRestoreEntryInvalid(t *testing.T) {
+	testCases := map[string]*http.Request{
+		"missing revision": newFormRequest(url.Values{
+			"action": {"restore"},
+			"url":    {"https://example.com/blog/p/1"},
+		}),
+		"json missing revision": newJSONRequest(`{"action": "restore", "url": "https://example.com/blog/p/1"}`),
+		"missing url":           newJSONRequest(`{"action": "restore", "revision": 2}`),
+	}
+
+	for name, req := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			db := &fakePostDB{}
+
+			handler := withScope("update", postHandler(db, nil, &fakeIdempotencyStore{}, nil))
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			resp := w.Result()
+			assert.Equal(http.StatusBadRequest, resp.StatusCode)
+			assert.Len(db.restored, 0)
+		})
+	}
+}
+
+func TestRestoreEntryMissingScope(t *testing.T) {
+	assert := assert.New(t)
+	db := &fakePostDB{}
+
+	handler := withScope("create", postHandler(db, nil, &fakeIdempotencyStore{}, nil))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, newJSONRequest(`{"action": "restore", "url": "https://example.com/blog/p/1", "revision": 2}`))
+
+	resp := w.Result()
+	assert.Equal(http.StatusUnauthorized, resp.StatusCode)
+	assert.Len(db.restored, 0)
+}
+
+func TestPurgeEntry(t *testing.T) {
+	testCases := map[string]*http.Request{
+		"url-encoded-form": newFormRequest(url.Values{
+			"action": {"purge"},
+			"url":    {"https://example.com/blog/p/1"},
+		}),
+		"json": newJSONRequest(`{"action": "purge", "url": "https://example.com/blog/p/1"}`),
+	}
+
+	for name, req := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			db := &fakePostDB{}
+
+			handler := withScope("delete", postHandler(db, nil, &fakeIdempotencyStore{}, nil))
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			resp := w.Result()
+			assert.Equal(http.StatusNoContent, resp.StatusCode)
+			assert.Equal([]string{"https://example.com/blog/p/1"}, db.purged)
+		})
+	}
+}
+
+func TestPurgeEntryMissingScope(t *testing.T) {
+	assert := assert.New(t)
+	db := &fakePostDB{}
+
+	handler := withScope("update", postHandler(db, nil, &fakeIdempotencyStore{}, nil))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, newJSONRequest(`{"action": "purge", "url": "https://example.com/blog/p/1"}`))
+
+	resp := w.Result()
+	assert.Equal(http.StatusUnauthorized, resp.StatusCode)
+	assert.Len(db.purged, 0)
+}
@@ -0,0 +1,259 @@
+package micropub
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"hawx.me/code/tally-ho/internal/mfutil"
+	"hawx.me/code/tally-ho/internal/posttype"
+)
+
+// validators check that the properties for a type of microformat make sense
+// before it is created. Types that are not listed here can not be created.
+var validators = map[string]func(data map[string][]any) error{
+	"entry":  validateEntry,
+	"event":  validateEvent,
+	"review": validateReview,
+}
+
+// propertyRules check each value given for a property, for both creates and
+// updates.
+var propertyRules = map[string]func(value any) error{
+	"published":    isDate,
+	"updated":      isDate,
+	"start":        isDate,
+	"end":          isDate,
+	"in-reply-to":  isURL,
+	"like-of":      isURL,
+	"repost-of":    isURL,
+	"bookmark-of":  isURL,
+	"syndication":  isURL,
+	"photo":        isMedia,
+	"video":        isMedia,
+	"audio":        isMedia,
+	"mp-photo-alt": isString,
+	"mp-slug":      isString,
+	"item":         isItem,
+	"rating":       isNumber,
+	"best":         isNumber,
+	"worst":        isNumber,
+	"rsvp":         isOneOf(posttype.RSVPs...),
+	"post-status":  isOneOf("published", "draft"),
+	"visibility":   isOneOf("public", "unlisted", "private"),
+}
+
+// validate returns an error describing the problem with data if it should not
+// be created.
+func validate(data map[string][]any) error {
+	h := "entry"
+	if len(data["h"]) > 0 {
+		s, ok := data["h"][0].(string)
+		if !ok {
+			return errors.New("h must be a string")
+		}
+		h = s
+	}
+
+	validator, ok := validators[h]
+	if !ok {
+		return fmt.Errorf("h-%s is not a supported type", h)
+	}
+
+	if err := validateProperties(data); err != nil {
+		return err
+	}
+
+	return validator(data)
+}
+
+// validateProperties returns an error describing the first value that does not
+// follow the rule for its property.
+func validateProperties(properties map[string][]any) error {
+	for _, key := range slices.Sorted(maps.Keys(properties)) {
+		rule, ok := propertyRules[key]
+		if !ok {
+			continue
+		}
+
+		for _, value := range properties[key] {
+			if err := rule(value); err != nil {
+				return fmt.Errorf("%s %w", key, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func validateEntry(data map[string][]any) error {
+	if len(data["rsvp"]) > 0 && len(data["in-reply-to"]) == 0 {
+		return errors.New("rsvp must be in-reply-to an event")
+	}
+
+	// only the fallback type can be discovered without its required
+	// properties, so this is checking that a note has something to show
+	return requireProperties(posttype.Discover(data), data)
+}
+
+func validateEvent(data map[string][]any) error {
+	if err := requireProperties("event", data); err != nil {
+		return err
+	}
+
+	if len(data["end"]) > 0 {
+		start, _ := mfutil.ParseDate(data["start"][0].(string))
+		end, _ := mfutil.ParseDate(data["end"][0].(string))
+
+		if end.Before(start) {
+			return errors.New("event must not end before it starts")
+		}
+	}
+
+	return nil
+}
+
+func validateReview(data map[string][]any) error {
+	if err := requireProperties("review", data); err != nil {
+		return err
+	}
+
+	rating, best, worst := number(data["rating"][0]), 5.0, 1.0
+	if len(data["best"]) > 0 {
+		best = number(data["best"][0])
+	}
+	if len(data["worst"]) > 0 {
+		worst = number(data["worst"][0])
+	}
+
+	if rating < worst || rating > best {
+		return fmt.Errorf("rating must be between %v and %v", worst, best)
+	}
+
+	return nil
+}
+
+// requireProperties checks that data has the properties required for the
+// post type kind.
+func requireProperties(kind string, data map[string][]any) error {
+	t, _ := posttype.Get(kind)
+	for _, property := range t.RequiredProperties {
+		if len(data[property]) == 0 {
+			return fmt.Errorf("%s must have %s", strings.ToLower(t.Name), property)
+		}
+	}
+
+	return nil
+}
+
+func isString(value any) error {
+	if _, ok := value.(string); !ok {
+		return errors.New("must be a string")
+	}
+
+	return nil
+}
+
+func isDate(value any) error {
+	s, ok := value.(string)
+	if !ok {
+		return errors.New("must be a string")
+	}
+
+	if _, err := mfutil.ParseDate(s); err != nil {
+		return errors.New("must be a date")
+	}
+
+	return nil
+}
+
+func isURL(value any) error {
+	s, ok := value.(string)
+	if !ok {
+		return errors.New("must be a string")
+	}
+
+	u, err := url.Parse(s)
+	if err != nil || !u.IsAbs() || u.Host == "" {
+		return errors.New("must be an absolute URL")
+	}
+
+	return nil
+}
+
+func isNumber(value any) error {
+	switch v := value.(type) {
+	case float64:
+		return nil
+	case string:
+		if _, err := strconv.ParseFloat(v, 64); err == nil {
+			return nil
+		}
+	}
+
+	return errors.New("must be a number")
+}
+
+// number returns the value of a property that has been checked with isNumber.
+func number(value any) float64 {
+	if s, ok := value.(string); ok {
+		f, _ := strconv.ParseFloat(s, 64)
+		return f
+	}
+
+	f, _ := value.(float64)
+	return f
+}
+
+// isItem checks for a url, or a microformat object such as a h-product.
+func isItem(value any) error {
+	obj, ok := value.(map[string]any)
+	if !ok {
+		return isURL(value)
+	}
+
+	if _, ok := obj["type"].([]any); !ok {
+		return errors.New("must have a type")
+	}
+
+	if _, ok := obj["properties"].(map[string]any); !ok {
+		return errors.New("must have properties")
+	}
+
+	return nil
+}
+
+// isMedia checks for a url, or an object with a url as its value and optional
+// alt text.
+func isMedia(value any) error {
+	obj, ok := value.(map[string]any)
+	if !ok {
+		return isURL(value)
+	}
+
+	if err := isURL(obj["value"]); err != nil {
+		return fmt.Errorf("value %w", err)
+	}
+
+	if alt, ok := obj["alt"]; ok {
+		if err := isString(alt); err != nil {
+			return fmt.Errorf("alt %w", err)
+		}
+	}
+
+	return nil
+}
+
+func isOneOf(allowed ...string) func(value any) error {
+	return func(value any) error {
+		if s, ok := value.(string); !ok || !slices.Contains(allowed, s) {
+			return fmt.Errorf("must be one of %v", allowed)
+		}
+
+		return nil
+	}
+}
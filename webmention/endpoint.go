@@ -18,6 +18,7 @@ import (
 
 type Blog interface {
 	Entry(url string) (data map[string][]interface{}, err error)
+	Redirect(url string) (string, error)
 	Mention(source string, data map[string][]interface{}) error
 	BaseURL() string
 }
@@ -71,6 +72,11 @@ func postHandler(blog Blog) http.HandlerFunc {
 }
 
 func processMention(mention webmention, blog Blog) error {
+	// mentions of an old url for a post are kept against its current url
+	if location, err := blog.Redirect(mention.target); err == nil {
+		mention.target = location
+	}
+
 	_, err := blog.Entry(mention.target)
 	if err != nil {
 		return errors.New("no such post at 'target'")
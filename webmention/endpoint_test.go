@@ -35,6 +35,14 @@ func (b *fakeBlog) Entry(url string) (map[string][]interface{}, error) {
 	return map[string][]interface{}{}, nil
 }
 
+func (b *fakeBlog) Redirect(url string) (string, error) {
+	if url != "http://example.com/old/post-id" {
+		return "", errors.New("what is that")
+	}
+
+	return "http://example.com/weblog/post-id", nil
+}
+
 func (b *fakeBlog) Mention(source string, data map[string][]interface{}) error {
 	b.ch <- mention{source, data}
 	return nil
@@ -114,6 +122,48 @@ func TestMention(t *testing.T) {
 	}
 }
 
+func TestMentionOfAlias(t *testing.T) {
+	assert := assert.New(t)
+
+	blog := &fakeBlog{ch: make(chan mention, 1)}
+
+	source := httptest.NewServer(stringHandler(`
+<div class="h-entry">
+  <h1 class="p-name">A reply to some post</h1>
+  <p>
+    In <a class="u-in-reply-to" href="http://example.com/old/post-id">this post</a>, I disagree.
+  </p>
+</div>
+`))
+	defer source.Close()
+
+	handler := Endpoint(blog)
+
+	req := newFormRequest(url.Values{
+		"source": {source.URL},
+		"target": {"http://example.com/old/post-id"},
+	})
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	resp := w.Result()
+	assert.Equal(http.StatusAccepted, resp.StatusCode)
+
+	select {
+	case m := <-blog.ch:
+		assert.Equal(source.URL, m.source)
+
+		assert.Equal(map[string][]interface{}{
+			"name":        {"A reply to some post"},
+			"in-reply-to": {"http://example.com/old/post-id"},
+			"hx-target":   {"http://example.com/weblog/post-id"},
+		}, m.data)
+	case <-time.After(waitTime):
+		t.Fatal("failed to get notified")
+	}
+}
+
 func TestMentionWhenPostUpdated(t *testing.T) {
 	assert := assert.New(t)
 